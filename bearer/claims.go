@@ -0,0 +1,137 @@
+package bearer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Claims holds the claims of a verified token.
+type Claims struct {
+	// Issuer is the "iss" claim, identifying the token issuer.
+	Issuer string
+
+	// Subject is the "sub" claim, identifying the caller.
+	Subject string
+
+	// ClientID is the "client_id" claim set by UAA.
+	ClientID string
+
+	// Audience is the "aud" claim.
+	Audience []string
+
+	// Scopes are the scopes granted to the caller, from the "scope"
+	// claim given either as a JSON array, as UAA does, or as a
+	// space-separated string.
+	Scopes []string
+
+	// ExpiresAt, NotBefore and IssuedAt are the "exp", "nbf" and "iat"
+	// claims. They are zero when the claim is not present.
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+
+	// Raw holds every claim of the token, as decoded from JSON.
+	Raw map[string]interface{}
+}
+
+// HasScope reports whether the scope was granted to the caller.
+func (c Claims) HasScope(scope string) bool {
+	for _, granted := range c.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type rawClaims struct {
+	Issuer    string      `json:"iss"`
+	Subject   string      `json:"sub"`
+	ClientID  string      `json:"client_id"`
+	Audience  stringList  `json:"aud"`
+	Scope     stringList  `json:"scope"`
+	ExpiresAt json.Number `json:"exp"`
+	NotBefore json.Number `json:"nbf"`
+	IssuedAt  json.Number `json:"iat"`
+}
+
+func parseClaims(payload []byte) (Claims, error) {
+	var raw rawClaims
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return Claims{}, InvalidTokenError("claims are malformed")
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(payload, &all); err != nil {
+		return Claims{}, InvalidTokenError("claims are malformed")
+	}
+
+	claims := Claims{
+		Issuer:   raw.Issuer,
+		Subject:  raw.Subject,
+		ClientID: raw.ClientID,
+		Audience: raw.Audience,
+		Scopes:   raw.Scope,
+		Raw:      all,
+	}
+
+	for _, field := range []struct {
+		name  string
+		value json.Number
+		time  *time.Time
+	}{
+		{"exp", raw.ExpiresAt, &claims.ExpiresAt},
+		{"nbf", raw.NotBefore, &claims.NotBefore},
+		{"iat", raw.IssuedAt, &claims.IssuedAt},
+	} {
+		if field.value == "" {
+			continue
+		}
+
+		seconds, err := field.value.Float64()
+		if err != nil {
+			return Claims{}, InvalidTokenError("claim \"" + field.name + "\" must be a number")
+		}
+		*field.time = time.Unix(0, int64(seconds*float64(time.Second)))
+	}
+
+	return claims, nil
+}
+
+// stringList decodes a JSON array of strings, or a single string holding
+// space-separated values.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		*l = values
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*l = strings.Fields(value)
+
+	return nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the claims.
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims of the token the request was
+// authenticated with. Brokers can use it with the Context method of the
+// request they are given.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(Claims)
+	return claims, ok
+}
@@ -0,0 +1,98 @@
+package bearer_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEnvoyBearerSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Envoy Bearer Suite")
+}
+
+var (
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+)
+
+var _ = BeforeSuite(func() {
+	var err error
+	rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).NotTo(HaveOccurred())
+
+	ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+})
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func keySetDocument() []byte {
+	document, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-key",
+				"use": "sig",
+				"n":   encode(rsaKey.N.Bytes()),
+				"e":   encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-key",
+				"crv": "P-256",
+				"x":   encode(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   encode(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			{
+				"kty": "oct",
+				"kid": "shared-key",
+				"k":   encode([]byte("secret")),
+			},
+		},
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	return document
+}
+
+// sign returns a token with the given claims, signed with the test RSA
+// key using RS256, or the test EC key using ES256.
+func sign(algorithm, keyID string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": algorithm, "kid": keyID, "typ": "JWT"})
+	Expect(err).NotTo(HaveOccurred())
+
+	payload, err := json.Marshal(claims)
+	Expect(err).NotTo(HaveOccurred())
+
+	signed := encode(header) + "." + encode(payload)
+	sum := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch algorithm {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sum[:])
+		Expect(err).NotTo(HaveOccurred())
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, sum[:])
+		Expect(err).NotTo(HaveOccurred())
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		panic(fmt.Sprintf("unexpected algorithm %s", algorithm))
+	}
+
+	return signed + "." + encode(signature)
+}
@@ -0,0 +1,284 @@
+package bearer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// UnknownKeyError is an error type used to indicate that a token was
+// signed with a key that is not part of the key set.
+type UnknownKeyError string
+
+// Error returns a string representation of the error message.
+func (e UnknownKeyError) Error() string {
+	return fmt.Sprintf("bearer: key %q is not in the key set", string(e))
+}
+
+// KeySource provides the public keys used to verify token signatures,
+// indexed by key ID.
+type KeySource interface {
+	Key(id string) (crypto.PublicKey, error)
+}
+
+// KeySet is a static set of public keys, parsed from a JSON Web Key Set
+// document. RSA and EC (P-256, P-384 and P-521) keys are supported; other
+// keys in the document are ignored.
+type KeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+// ParseKeySet parses a JSON Web Key Set document, as described in
+// RFC 7517.
+func ParseKeySet(document []byte) (KeySet, error) {
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(document, &jwks); err != nil {
+		return KeySet{}, fmt.Errorf("bearer: key set is malformed: %s", err)
+	}
+
+	set := KeySet{keys: map[string]crypto.PublicKey{}}
+	for _, key := range jwks.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var public crypto.PublicKey
+		var err error
+		switch key.KeyType {
+		case "RSA":
+			public, err = rsaKey(key.N, key.E)
+		case "EC":
+			public, err = ecKey(key.Curve, key.X, key.Y)
+		default:
+			continue
+		}
+		if err != nil {
+			return KeySet{}, fmt.Errorf("bearer: key %q: %s", key.KeyID, err)
+		}
+
+		set.keys[key.KeyID] = public
+	}
+
+	return set, nil
+}
+
+// LoadKeySetFile reads and parses the JSON Web Key Set document at path.
+func LoadKeySetFile(path string) (KeySet, error) {
+	document, err := ioutil.ReadFile(path)
+	if err != nil {
+		return KeySet{}, err
+	}
+
+	return ParseKeySet(document)
+}
+
+// Key returns the key with the given ID. When the token does not name a
+// key, and the set holds a single key, that key is returned.
+func (s KeySet) Key(id string) (crypto.PublicKey, error) {
+	if key, ok := s.keys[id]; ok {
+		return key, nil
+	}
+
+	if id == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, nil
+		}
+	}
+
+	return nil, UnknownKeyError(id)
+}
+
+func rsaKey(n, e string) (crypto.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(modulus) == 0 || len(exponent) == 0 || len(exponent) > 4 {
+		return nil, fmt.Errorf("RSA key is malformed")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
+
+func ecKey(crv, x, y string) (crypto.PublicKey, error) {
+	var curve elliptic.Curve
+	switch crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("curve %q is not supported", crv)
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil {
+		return nil, err
+	}
+
+	yBytes, err := base64.RawURLEncoding.DecodeString(y)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, fmt.Errorf("EC key is not on curve %s", crv)
+	}
+
+	return key, nil
+}
+
+// RemoteKeySet is a KeySource fetching the JSON Web Key Set document
+// served at a URL, such as UAA's /token_keys endpoint. The document is
+// fetched on first use, refreshed once it is older than the refresh
+// interval, and fetched again when a token names an unknown key, so that
+// signing key rotations are picked up promptly. The document is fetched at
+// most once a minute, and the last document fetched keeps being used while
+// it cannot be refreshed. Only one fetch runs at a time; callers whose key
+// is already known do not wait for it. It is safe for concurrent use.
+type RemoteKeySet struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	minInterval     time.Duration
+	now             func() time.Time
+
+	mutex       sync.Mutex
+	set         KeySet
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetchErr    error
+	fetching    chan struct{}
+}
+
+// NewRemoteKeySet returns a RemoteKeySet fetching the document at url with
+// the given client, or with a client timing out after 10 seconds if client
+// is nil. The document is refreshed every hour.
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &RemoteKeySet{
+		url:             url,
+		client:          client,
+		refreshInterval: time.Hour,
+		minInterval:     time.Minute,
+		now:             time.Now,
+	}
+}
+
+// Key returns the key with the given ID, fetching the document if needed.
+func (s *RemoteKeySet) Key(id string) (crypto.PublicKey, error) {
+	s.mutex.Lock()
+
+	now := s.now()
+	if s.fetching == nil && s.needsFetch(id, now) {
+		s.attemptedAt = now
+		s.fetching = make(chan struct{})
+		s.mutex.Unlock()
+
+		set, err := s.fetch()
+
+		s.mutex.Lock()
+		s.fetchErr = err
+		if err == nil {
+			s.set = set
+			s.fetchedAt = now
+		}
+		close(s.fetching)
+		s.fetching = nil
+	}
+
+	if fetching := s.fetching; fetching != nil && !s.known(id) {
+		s.mutex.Unlock()
+		<-fetching
+		s.mutex.Lock()
+	}
+	defer s.mutex.Unlock()
+
+	if s.fetchedAt.IsZero() {
+		return nil, s.fetchErr
+	}
+
+	return s.set.Key(id)
+}
+
+// known reports whether the last document fetched contains the key.
+func (s *RemoteKeySet) known(id string) bool {
+	if s.fetchedAt.IsZero() {
+		return false
+	}
+
+	_, err := s.set.Key(id)
+	return err == nil
+}
+
+func (s *RemoteKeySet) needsFetch(id string, now time.Time) bool {
+	if !s.attemptedAt.IsZero() && now.Sub(s.attemptedAt) < s.minInterval {
+		return false
+	}
+
+	if s.fetchedAt.IsZero() || now.Sub(s.fetchedAt) >= s.refreshInterval {
+		return true
+	}
+
+	_, err := s.set.Key(id)
+	_, unknown := err.(UnknownKeyError)
+	return unknown
+}
+
+func (s *RemoteKeySet) fetch() (KeySet, error) {
+	response, err := s.client.Get(s.url)
+	if err != nil {
+		return KeySet{}, fmt.Errorf("bearer: key set could not be fetched: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return KeySet{}, fmt.Errorf("bearer: key set could not be fetched: unexpected status %d", response.StatusCode)
+	}
+
+	document, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return KeySet{}, fmt.Errorf("bearer: key set could not be fetched: %s", err)
+	}
+
+	return ParseKeySet(document)
+}
@@ -0,0 +1,78 @@
+package bearer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RemoteKeySet refreshes", func() {
+	var server *httptest.Server
+	var hang chan struct{}
+	var keys *RemoteKeySet
+	var now time.Time
+
+	BeforeEach(func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		encode := base64.RawURLEncoding.EncodeToString
+		document := fmt.Sprintf(`{"keys": [{"kty": "EC", "kid": "ec-key", "crv": "P-256", "x": %q, "y": %q}]}`,
+			encode(key.X.FillBytes(make([]byte, 32))), encode(key.Y.FillBytes(make([]byte, 32))))
+
+		hang = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if hang != nil {
+				<-hang
+			}
+			w.Write([]byte(document))
+		}))
+
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		keys = NewRemoteKeySet(server.URL, nil)
+		keys.now = func() time.Time { return now }
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("keeps answering with known keys while a refresh hangs", func() {
+		_, err := keys.Key("ec-key")
+		Expect(err).NotTo(HaveOccurred())
+
+		now = now.Add(2 * time.Hour)
+		hang = make(chan struct{})
+		defer close(hang)
+
+		refreshed := make(chan error, 1)
+		go func() {
+			_, err := keys.Key("ec-key")
+			refreshed <- err
+		}()
+		Eventually(func() bool {
+			keys.mutex.Lock()
+			defer keys.mutex.Unlock()
+			return keys.fetching != nil
+		}).Should(BeTrue())
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := keys.Key("ec-key")
+			done <- err
+		}()
+		Eventually(done).Should(Receive(BeNil()))
+		Consistently(refreshed).ShouldNot(Receive())
+	})
+
+	It("times out fetches by default", func() {
+		Expect(keys.client.Timeout).To(Equal(10 * time.Second))
+	})
+})
@@ -0,0 +1,139 @@
+package bearer_test
+
+import (
+	"crypto/rsa"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pivotal-cf-experimental/envoy/bearer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeySet", func() {
+	It("parses RSA and EC signing keys, ignoring other keys", func() {
+		keys, err := bearer.ParseKeySet(keySetDocument())
+		Expect(err).NotTo(HaveOccurred())
+
+		key, err := keys.Key("rsa-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.(*rsa.PublicKey).Equal(&rsaKey.PublicKey)).To(BeTrue())
+
+		key, err = keys.Key("ec-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ecKey.PublicKey.Equal(key)).To(BeTrue())
+
+		_, err = keys.Key("shared-key")
+		Expect(err).To(Equal(bearer.UnknownKeyError("shared-key")))
+	})
+
+	It("rejects malformed documents", func() {
+		_, err := bearer.ParseKeySet([]byte(`{"keys": [{"kty": "EC", "kid": "bad", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+		Expect(err).To(MatchError(`bearer: key "bad": EC key is not on curve P-256`))
+
+		_, err = bearer.ParseKeySet([]byte(`not json`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("loads documents from files", func() {
+		dir, err := ioutil.TempDir("", "bearer")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "jwks.json")
+		Expect(ioutil.WriteFile(path, keySetDocument(), 0600)).To(Succeed())
+
+		keys, err := bearer.LoadKeySetFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = keys.Key("rsa-key")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("RemoteKeySet", func() {
+	var server *httptest.Server
+	var fetches int
+	var document []byte
+
+	BeforeEach(func() {
+		fetches = 0
+		document = []byte(`{"keys": []}`)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fetches++
+			w.Write(document)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("fetches the document on first use", func() {
+		document = keySetDocument()
+		keys := bearer.NewRemoteKeySet(server.URL, nil)
+
+		_, err := keys.Key("rsa-key")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = keys.Key("ec-key")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fetches).To(Equal(1))
+	})
+
+	It("does not fetch the document again right away for unknown keys", func() {
+		keys := bearer.NewRemoteKeySet(server.URL, nil)
+
+		_, err := keys.Key("rsa-key")
+		Expect(err).To(Equal(bearer.UnknownKeyError("rsa-key")))
+
+		document = keySetDocument()
+		_, err = keys.Key("rsa-key")
+		Expect(err).To(Equal(bearer.UnknownKeyError("rsa-key")))
+
+		Expect(fetches).To(Equal(1))
+	})
+
+	It("fetches the document once for concurrent callers", func() {
+		release := make(chan struct{})
+		var mutex sync.Mutex
+		var concurrentFetches int
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			<-release
+			mutex.Lock()
+			concurrentFetches++
+			mutex.Unlock()
+			w.Write(keySetDocument())
+		})
+		keys := bearer.NewRemoteKeySet(server.URL, nil)
+
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			go func() {
+				_, err := keys.Key("rsa-key")
+				errs <- err
+			}()
+		}
+		close(release)
+
+		for i := 0; i < 10; i++ {
+			Expect(<-errs).NotTo(HaveOccurred())
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(concurrentFetches).To(Equal(1))
+	})
+
+	It("returns an error when the document cannot be fetched", func() {
+		server.Close()
+		keys := bearer.NewRemoteKeySet(server.URL, nil)
+
+		_, err := keys.Key("rsa-key")
+		Expect(err).To(MatchError(ContainSubstring("bearer: key set could not be fetched")))
+	})
+})
@@ -0,0 +1,212 @@
+package bearer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// InvalidTokenError is an error type used to indicate that a token is
+// malformed, is not signed by a trusted key, or is not meant for the
+// broker.
+type InvalidTokenError string
+
+// Error returns a string representation of the error message.
+func (e InvalidTokenError) Error() string {
+	return "bearer: invalid token: " + string(e)
+}
+
+// InsufficientScopeError is an error type used to indicate that a valid
+// token was not granted a required scope.
+type InsufficientScopeError string
+
+// Error returns a string representation of the error message.
+func (e InsufficientScopeError) Error() string {
+	return fmt.Sprintf("bearer: token is missing required scope %q", string(e))
+}
+
+// Config describes the tokens accepted by a Verifier.
+type Config struct {
+	// Issuer is the required "iss" claim. It is not checked when empty.
+	Issuer string
+
+	// Audience must be one of the values of the "aud" claim. It is not
+	// checked when empty.
+	Audience string
+
+	// RequiredScopes must all be granted to the caller.
+	RequiredScopes []string
+
+	// Leeway is the clock skew tolerated when checking the "exp" and
+	// "nbf" claims.
+	Leeway time.Duration
+}
+
+// Verifier verifies JSON Web Tokens signed with RS256, RS384, RS512,
+// PS256, PS384, PS512, ES256, ES384 or ES512. Unsigned tokens, and tokens
+// signed with a shared secret, are always rejected.
+type Verifier struct {
+	keys   KeySource
+	config Config
+	now    func() time.Time
+}
+
+// NewVerifier returns a Verifier checking token signatures against the
+// keys of the given source, and token claims against the config.
+func NewVerifier(keys KeySource, config Config) Verifier {
+	return Verifier{
+		keys:   keys,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Verify checks the signature and claims of the token, in the compact
+// serialization, and returns its claims. The error is an
+// InsufficientScopeError when the token is valid but lacks a required
+// scope, and an InvalidTokenError or UnknownKeyError otherwise.
+func (v Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, InvalidTokenError("token must have three parts")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Claims{}, InvalidTokenError("header is not Base64URL encoded")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return Claims{}, InvalidTokenError("header is malformed")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, InvalidTokenError("signature is not Base64URL encoded")
+	}
+
+	key, err := v.keys.Key(header.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if err := verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, InvalidTokenError("payload is not Base64URL encoded")
+	}
+
+	claims, err := parseClaims(payload)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return claims, v.check(claims)
+}
+
+func (v Verifier) check(claims Claims) error {
+	now := v.now()
+
+	if claims.ExpiresAt.IsZero() {
+		return InvalidTokenError("token has no expiry")
+	}
+
+	if now.After(claims.ExpiresAt.Add(v.config.Leeway)) {
+		return InvalidTokenError("token has expired")
+	}
+
+	if !claims.NotBefore.IsZero() && now.Before(claims.NotBefore.Add(-v.config.Leeway)) {
+		return InvalidTokenError("token is not valid yet")
+	}
+
+	if v.config.Issuer != "" && claims.Issuer != v.config.Issuer {
+		return InvalidTokenError(fmt.Sprintf("issuer %q is not trusted", claims.Issuer))
+	}
+
+	if v.config.Audience != "" && !contains(claims.Audience, v.config.Audience) {
+		return InvalidTokenError("token is not meant for this audience")
+	}
+
+	for _, scope := range v.config.RequiredScopes {
+		if !claims.HasScope(scope) {
+			return InsufficientScopeError(scope)
+		}
+	}
+
+	return nil
+}
+
+type algorithm struct {
+	family string
+	hash   crypto.Hash
+}
+
+var algorithms = map[string]algorithm{
+	"RS256": {"RS", crypto.SHA256},
+	"RS384": {"RS", crypto.SHA384},
+	"RS512": {"RS", crypto.SHA512},
+	"PS256": {"PS", crypto.SHA256},
+	"PS384": {"PS", crypto.SHA384},
+	"PS512": {"PS", crypto.SHA512},
+	"ES256": {"ES", crypto.SHA256},
+	"ES384": {"ES", crypto.SHA384},
+	"ES512": {"ES", crypto.SHA512},
+}
+
+func verifySignature(name string, key crypto.PublicKey, signed string, signature []byte) error {
+	alg, ok := algorithms[name]
+	if !ok {
+		return InvalidTokenError(fmt.Sprintf("algorithm %q is not supported", name))
+	}
+
+	digest := alg.hash.New()
+	digest.Write([]byte(signed))
+	sum := digest.Sum(nil)
+
+	var verified bool
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg.family {
+		case "RS":
+			verified = rsa.VerifyPKCS1v15(key, alg.hash, sum, signature) == nil
+		case "PS":
+			verified = rsa.VerifyPSS(key, alg.hash, sum, signature, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if alg.family == "ES" && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			verified = ecdsa.Verify(key, sum, r, s)
+		}
+	}
+
+	if !verified {
+		return InvalidTokenError("signature is invalid")
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
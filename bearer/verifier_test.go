@@ -0,0 +1,154 @@
+package bearer_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/bearer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verifier", func() {
+	var verifier bearer.Verifier
+	var claims map[string]interface{}
+
+	BeforeEach(func() {
+		keys, err := bearer.ParseKeySet(keySetDocument())
+		Expect(err).NotTo(HaveOccurred())
+
+		verifier = bearer.NewVerifier(keys, bearer.Config{
+			Issuer:         "https://uaa.example.com/oauth/token",
+			Audience:       "my-broker",
+			RequiredScopes: []string{"my-broker.admin"},
+		})
+
+		claims = map[string]interface{}{
+			"iss":       "https://uaa.example.com/oauth/token",
+			"sub":       "some-user",
+			"client_id": "cf",
+			"aud":       []string{"cloud_controller", "my-broker"},
+			"scope":     []string{"cloud_controller.read", "my-broker.admin"},
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"iat":       time.Now().Unix(),
+			"zid":       "uaa",
+		}
+	})
+
+	It("verifies RS256 tokens, and returns their claims", func() {
+		verified, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(verified.Issuer).To(Equal("https://uaa.example.com/oauth/token"))
+		Expect(verified.Subject).To(Equal("some-user"))
+		Expect(verified.ClientID).To(Equal("cf"))
+		Expect(verified.Audience).To(Equal([]string{"cloud_controller", "my-broker"}))
+		Expect(verified.Scopes).To(Equal([]string{"cloud_controller.read", "my-broker.admin"}))
+		Expect(verified.ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+		Expect(verified.Raw).To(HaveKeyWithValue("zid", "uaa"))
+		Expect(verified.HasScope("my-broker.admin")).To(BeTrue())
+	})
+
+	It("verifies ES256 tokens", func() {
+		_, err := verifier.Verify(sign("ES256", "ec-key", claims))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("accepts scopes and audiences given as strings", func() {
+		claims["aud"] = "my-broker"
+		claims["scope"] = "openid my-broker.admin"
+
+		verified, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verified.Scopes).To(Equal([]string{"openid", "my-broker.admin"}))
+	})
+
+	It("rejects tokens with a tampered payload", func() {
+		token := sign("RS256", "rsa-key", claims)
+		claims["sub"] = "someone-else"
+		tampered := sign("RS256", "rsa-key", claims)
+
+		original, modified := strings.Split(token, "."), strings.Split(tampered, ".")
+
+		_, err := verifier.Verify(original[0] + "." + modified[1] + "." + original[2])
+		Expect(err).To(Equal(bearer.InvalidTokenError("signature is invalid")))
+	})
+
+	It("rejects tokens signed with a key of another type", func() {
+		_, err := verifier.Verify(sign("ES256", "rsa-key", claims))
+		Expect(err).To(Equal(bearer.InvalidTokenError("signature is invalid")))
+	})
+
+	It("rejects unsigned tokens", func() {
+		_, err := verifier.Verify(encode([]byte(`{"alg":"none"}`)) + "." + encode([]byte(`{}`)) + ".")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects tokens signed with a shared secret", func() {
+		_, err := verifier.Verify(encode([]byte(`{"alg":"HS256","kid":"rsa-key"}`)) + "." + encode([]byte(`{}`)) + "." + encode([]byte("mac")))
+		Expect(err).To(Equal(bearer.InvalidTokenError(`algorithm "HS256" is not supported`)))
+	})
+
+	It("rejects tokens signed with unknown keys", func() {
+		_, err := verifier.Verify(sign("RS256", "other-key", claims))
+		Expect(err).To(Equal(bearer.UnknownKeyError("other-key")))
+	})
+
+	It("rejects malformed tokens", func() {
+		_, err := verifier.Verify("not-a-token")
+		Expect(err).To(Equal(bearer.InvalidTokenError("token must have three parts")))
+	})
+
+	It("rejects expired tokens", func() {
+		claims["exp"] = time.Now().Add(-time.Minute).Unix()
+
+		_, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).To(Equal(bearer.InvalidTokenError("token has expired")))
+	})
+
+	It("rejects tokens without an expiry", func() {
+		delete(claims, "exp")
+
+		_, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).To(Equal(bearer.InvalidTokenError("token has no expiry")))
+	})
+
+	It("rejects tokens that are not valid yet", func() {
+		claims["nbf"] = time.Now().Add(time.Minute).Unix()
+
+		_, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).To(Equal(bearer.InvalidTokenError("token is not valid yet")))
+	})
+
+	It("tolerates clock skew within the leeway", func() {
+		keys, err := bearer.ParseKeySet(keySetDocument())
+		Expect(err).NotTo(HaveOccurred())
+		verifier = bearer.NewVerifier(keys, bearer.Config{Leeway: time.Minute})
+		claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+
+		_, err = verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects tokens from other issuers", func() {
+		claims["iss"] = "https://evil.example.com"
+
+		_, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).To(Equal(bearer.InvalidTokenError(`issuer "https://evil.example.com" is not trusted`)))
+	})
+
+	It("rejects tokens for other audiences", func() {
+		claims["aud"] = []string{"cloud_controller"}
+
+		_, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).To(Equal(bearer.InvalidTokenError("token is not meant for this audience")))
+	})
+
+	It("rejects tokens missing a required scope", func() {
+		claims["scope"] = []string{"cloud_controller.read"}
+
+		_, err := verifier.Verify(sign("RS256", "rsa-key", claims))
+		Expect(err).To(Equal(bearer.InsufficientScopeError("my-broker.admin")))
+	})
+})
@@ -1,6 +1,10 @@
 package envoy
 
-import "github.com/pivotal-cf-experimental/envoy/domain"
+import (
+	"context"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
 
 // Broker defines the interface that makes up a Service Broker for CloudFoundry.
 // The Broker interface is the combined interface including all of the expected
@@ -14,18 +18,50 @@ type Broker interface {
 	Deprovisioner
 }
 
+// MinimalBroker defines the least a broker must implement to be served by
+// NewBrokerHandler. The operations the broker supports are detected from
+// the Provisioner, Binder, Unbinder, Deprovisioner or AsyncDeprovisioner,
+// and LastOperationer interfaces it implements; requests for the others
+// are answered with a 501 Not Implemented response. A broker offering
+// bindable services must implement both Binder and Unbinder.
+type MinimalBroker interface {
+	Cataloger
+	Credentialer
+}
+
 // Cataloger defines the interface for a broker component providing the catalogl
 // information.
 type Cataloger interface {
 	Catalog() domain.Catalog
 }
 
+// VersionedCataloger may be implemented by a Cataloger whose catalog
+// changes at runtime, to report the version of its catalog. The version
+// must change whenever the catalog does. The serialized catalog is then
+// cached until the version changes; otherwise, the catalog is requested
+// and serialized for every request.
+type VersionedCataloger interface {
+	CatalogVersion() uint64
+}
+
 // Credentialer defines the interface for the Basic Auth credentials required to
 // interact with the service broker.
 type Credentialer interface {
 	Credentials() (username, password string)
 }
 
+// MultiCredentialer may be implemented by a Broker, in addition to
+// Credentialer, to accept several Basic Auth credential pairs at once.
+// Requests authenticated with any of the returned pairs are accepted, and
+// the Credentials method is not consulted. The pairs are requested for
+// every request, so credentials can be rotated without downtime: return
+// both the old and the new pair until the platform has been updated, then
+// drop the old pair. Passwords may be given as bcrypt or argon2id hashes
+// instead of plain text; see domain.Credentials.
+type MultiCredentialer interface {
+	AllCredentials() []domain.Credentials
+}
+
 // Provisioner defines the interface for a request to provision a service.
 type Provisioner interface {
 	Provision(domain.ProvisionRequest) (domain.ProvisionResponse, error)
@@ -36,6 +72,23 @@ type Deprovisioner interface {
 	Deprovision(domain.DeprovisionRequest) error
 }
 
+// AsyncDeprovisioner may be implemented by a broker, instead of or in
+// addition to Deprovisioner, to deprovision service instances in the
+// background. It is then called for every deprovision request, and may
+// only answer asynchronously when the request AcceptsIncomplete.
+type AsyncDeprovisioner interface {
+	DeprovisionAsync(domain.DeprovisionRequest) (domain.DeprovisionResponse, error)
+}
+
+// LastOperationer may be implemented by a broker provisioning or
+// deprovisioning service instances asynchronously, to report the outcome
+// of the operations the platform polls for. A service instance found to
+// be gone while it is being deprovisioned should be reported with a
+// domain.ServiceInstanceNotFoundError.
+type LastOperationer interface {
+	LastOperation(domain.LastOperationRequest) (domain.LastOperationResponse, error)
+}
+
 // Binder defines the interface for a request to bind a service.
 type Binder interface {
 	Bind(domain.BindRequest) (domain.BindResponse, error)
@@ -45,3 +98,18 @@ type Binder interface {
 type Unbinder interface {
 	Unbind(domain.UnbindRequest) error
 }
+
+// HealthChecker may be implemented by a Broker to report whether it can
+// serve requests, for example whether its backend is reachable. It is
+// called for every readiness probe, so it should be quick.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// Drainer may be implemented by a Broker doing work in the background,
+// such as asynchronous operations. When a Server shuts down, Drain is
+// called once the requests in flight have been served; it should finish
+// or checkpoint the background work before the context is done.
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
@@ -1,36 +1,329 @@
 package envoy
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/pivotal-cf-experimental/envoy/domain"
 	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
 	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"github.com/pivotal-cf-experimental/envoy/internal/throttle"
+	"github.com/pivotal-cf-experimental/envoy/secrets"
 )
 
+type route struct {
+	operation string
+	method    string
+	path      string
+	handler   http.Handler
+}
+
 // NewBrokerHandler returns an http.Handler that can be bound used to
-// serve HTTP requests for the CloudFoundry service broker API.
-func NewBrokerHandler(broker Broker) http.Handler {
-	catalogHandler := handlers.NewCatalogHandler(broker)
-	provisionHandler := handlers.NewProvisionHandler(broker)
-	bindHandler := handlers.NewBindHandler(broker)
-	unbindHandler := handlers.NewUnbindHandler(broker)
-	deprovisionHandler := handlers.NewDeprovisionHandler(broker)
+// serve HTTP requests for the CloudFoundry service broker API. Without
+// options, every route is authenticated with the credentials of the broker.
+// The broker only needs to implement the operations it supports; see
+// MinimalBroker. It panics when the broker or options are invalid, as
+// described by NewServer, which returns an error instead.
+func NewBrokerHandler(broker MinimalBroker, options ...Option) http.Handler {
+	config := newConfig(options)
+	if err := checkConfig(broker, config); err != nil {
+		panic(err)
+	}
+
+	bodyPolicy := handlers.BodyPolicy{
+		MaxBytes: config.maxBodySize,
+		Strict:   config.strictDecoding,
+	}
+	idPolicy := handlers.IDPolicy{
+		MaxLength: config.maxIDLength,
+		Pattern:   config.idPattern,
+	}
+
+	var async *asyncOperations
+	if config.metrics != nil {
+		async = newAsyncOperations(config.metrics)
+	}
+
+	var provision http.Handler = handlers.NewUnsupportedHandler("provision")
+	if provisioner, ok := broker.(Provisioner); ok {
+		provisioner = redactingProvisioner{provisioner, config.redactor}
+		if config.metrics != nil {
+			provisioner = instrumentedProvisioner{provisioner, config.metrics, async}
+		}
+		provision = handlers.NewProvisionHandler(provisioner, bodyPolicy, idPolicy)
+	}
+
+	var bind http.Handler = handlers.NewUnsupportedHandler("bind")
+	if binder, ok := broker.(Binder); ok {
+		if config.secretStore != nil {
+			binder = secrets.NewReferenceBinder(binder, config.secretStore, config.secretNamespace)
+		}
+		binder = redactingBinder{binder, config.redactor}
+		if config.metrics != nil {
+			binder = instrumentedBinder{binder, config.metrics}
+		}
+		bind = handlers.NewBindHandler(binder, bodyPolicy, idPolicy)
+	}
+
+	var unbind http.Handler = handlers.NewUnsupportedHandler("unbind")
+	if unbinder, ok := broker.(Unbinder); ok {
+		if config.secretStore != nil {
+			unbinder = secrets.NewReferenceUnbinder(unbinder, config.secretStore, config.secretNamespace)
+		}
+		unbinder = redactingUnbinder{unbinder, config.redactor}
+		if config.metrics != nil {
+			unbinder = instrumentedUnbinder{unbinder, config.metrics}
+		}
+		unbind = handlers.NewUnbindHandler(unbinder, idPolicy)
+	}
+
+	var deprovisioner AsyncDeprovisioner
+	if d, ok := broker.(AsyncDeprovisioner); ok {
+		deprovisioner = d
+	} else if d, ok := broker.(Deprovisioner); ok {
+		deprovisioner = syncDeprovisioner{d}
+	}
+
+	var deprovision http.Handler = handlers.NewUnsupportedHandler("deprovision")
+	if deprovisioner != nil {
+		deprovisioner = redactingDeprovisioner{deprovisioner, config.redactor}
+		if config.metrics != nil {
+			deprovisioner = instrumentedDeprovisioner{deprovisioner, config.metrics, async}
+		}
+		deprovision = handlers.NewDeprovisionHandler(deprovisioner, idPolicy)
+	}
+
+	var lastOperation http.Handler = handlers.NewUnsupportedHandler("last_operation")
+	if lastOperationer, ok := broker.(LastOperationer); ok {
+		lastOperationer = redactingLastOperationer{lastOperationer, config.redactor}
+		if config.metrics != nil {
+			lastOperationer = instrumentedLastOperationer{lastOperationer, async}
+		}
+		lastOperation = handlers.NewLastOperationHandler(lastOperationer, idPolicy)
+	}
+
+	routes := []route{
+		{"catalog", "GET", "/v2/catalog", handlers.NewCatalogHandler(broker)},
+		{"provision", "PUT", "/v2/service_instances/{instance_id}", provision},
+		{"bind", "PUT", "/v2/service_instances/{instance_id}/service_bindings/{binding_id}", bind},
+		{"unbind", "DELETE", "/v2/service_instances/{instance_id}/service_bindings/{binding_id}", unbind},
+		{"deprovision", "DELETE", "/v2/service_instances/{instance_id}", deprovision},
+		{"last_operation", "GET", "/v2/service_instances/{instance_id}/last_operation", lastOperation},
+	}
+
+	routes = append(routes, config.routes...)
+
+	if config.metrics != nil {
+		routes = append(routes, route{"metrics", "GET", "/metrics", config.metrics})
+	}
+
+	const maintenancePath = "/admin/maintenance"
+	if config.maintenance != nil {
+		maintenanceHandler := handlers.NewMaintenanceHandler(config.maintenance, bodyPolicy)
+		routes = append(routes,
+			route{"maintenance", "GET", maintenancePath, maintenanceHandler},
+			route{"set_maintenance", "PUT", maintenancePath, maintenanceHandler},
+		)
+	}
+
+	panicLogger := config.logger
+	if panicLogger == nil {
+		panicLogger = slog.Default()
+	}
 
-	routes := map[string]http.Handler{
-		"GET /v2/catalog":                                                          middleware.NewAuthenticator(catalogHandler, broker),
-		"PUT /v2/service_instances/{instance_id}":                                  middleware.NewAuthenticator(provisionHandler, broker),
-		"PUT /v2/service_instances/{instance_id}/service_bindings/{binding_id}":    middleware.NewAuthenticator(bindHandler, broker),
-		"DELETE /v2/service_instances/{instance_id}/service_bindings/{binding_id}": middleware.NewAuthenticator(unbindHandler, broker),
-		"DELETE /v2/service_instances/{instance_id}":                               middleware.NewAuthenticator(deprovisionHandler, broker),
+	if err := checkOperations(broker, broker.Catalog()); err != nil {
+		panicLogger.Warn("the catalog does not match the operations of the broker", "error", err)
 	}
 
-	router := mux.NewRouter()
-	for endpoint, handler := range routes {
-		parts := strings.Split(endpoint, " ")
-		router.Handle(parts[1], handler).Methods(parts[0])
+	var limiter *throttle.Limiter
+	if config.rateLimit > 0 && config.rateBurst > 0 {
+		limiter = throttle.NewLimiter(config.rateLimit, config.rateBurst)
+	}
+
+	var lockout *throttle.Lockout
+	if config.lockoutFailures > 0 && config.lockoutDuration > 0 {
+		lockout = throttle.NewLockout(config.lockoutFailures, config.lockoutDuration)
+	}
+
+	observe := func(handler http.Handler, operation string) http.Handler {
+		handler = middleware.NewRecoverer(handler, panicLogger, config.panicHook)
+		if config.metrics != nil {
+			handler = middleware.NewMetrics(handler, config.metrics, operation)
+		}
+		if config.logger != nil {
+			handler = middleware.NewLogger(handler, config.logger, config.redactor)
+		}
+		if config.tracerProvider != nil {
+			handler = middleware.NewTracer(handler, config.tracerProvider, config.propagator, operation)
+		}
+		return middleware.NewRequestID(handler)
+	}
+
+	authenticate := config.authenticator
+	if authenticate == nil {
+		verifications := middleware.NewVerificationCache()
+		authenticate = func(handler http.Handler) http.Handler {
+			if config.bearerVerifier != nil {
+				return middleware.NewBearerAuthenticator(handler, config.bearerVerifier)
+			}
+			return middleware.NewAuthenticator(handler, broker, verifications)
+		}
+	}
+
+	router := mux.NewRouter().UseEncodedPath()
+	for _, route := range routes {
+		handler := chain(route.handler, config.routeMiddleware[route.operation])
+		if config.maintenance != nil && route.path != maintenancePath {
+			handler = middleware.NewMaintenance(handler, config.maintenance)
+		}
+		if limit := config.concurrency[route.operation]; limit > 0 {
+			handler = middleware.NewConcurrencyLimiter(handler, limit)
+		}
+		if lockout != nil {
+			handler = middleware.NewAuthenticated(handler)
+		}
+		handler = authenticate(handler)
+		if config.certPolicy != nil {
+			handler = middleware.NewCertificateAuthorizer(handler, authorizer(config.certPolicy, route.operation))
+		}
+		if lockout != nil {
+			handler = middleware.NewLockout(handler, lockout, middleware.ClientKey(config.clientKey))
+		}
+		if limiter != nil {
+			handler = middleware.NewRateLimiter(handler, limiter, middleware.ClientKey(config.clientKey))
+		}
+		if config.gate != nil {
+			handler = middleware.NewDrainer(handler, config.gate)
+		}
+		handler = observe(chain(handler, config.middleware), route.operation)
+
+		router.Handle(config.pathPrefix+route.path, handler).Methods(route.method).Name(route.operation)
+	}
+
+	if config.healthEndpoints {
+		public := []route{
+			{"healthz", "GET", "/healthz", handlers.NewHealthHandler()},
+			{"readyz", "GET", "/readyz", handlers.NewReadinessHandler(readinessChecks(broker, config.gate)...)},
+		}
+		for _, route := range public {
+			handler := chain(route.handler, config.routeMiddleware[route.operation])
+			handler = observe(chain(handler, config.middleware), route.operation)
+
+			router.Handle(config.pathPrefix+route.path, handler).Methods(route.method).Name(route.operation)
+		}
 	}
 
 	return router
 }
+
+// readinessChecks returns the checks run by the readiness endpoint: the
+// catalog must be valid, the broker healthy if it implements
+// HealthChecker, and the server must not be shutting down.
+func readinessChecks(broker MinimalBroker, gate *middleware.Gate) []handlers.Check {
+	checks := []handlers.Check{
+		{Name: "catalog", Check: func(context.Context) error {
+			catalog := broker.Catalog()
+			if err := catalog.Validate(); err != nil {
+				return err
+			}
+			return checkOperations(broker, catalog)
+		}},
+	}
+
+	if checker, ok := broker.(HealthChecker); ok {
+		checks = append(checks, handlers.Check{Name: "broker", Check: checker.CheckHealth})
+	}
+
+	if gate != nil {
+		checks = append(checks, handlers.Check{Name: "shutdown", Check: func(context.Context) error {
+			if gate.Closed() {
+				return errShuttingDown
+			}
+			return nil
+		}})
+	}
+
+	return checks
+}
+
+// checkConfig returns an error when the broker cannot be served with the
+// configuration, such as when it accepts password hashes that cannot be
+// verified, or when secrets cannot be stored under the namespace given to
+// WithSecretStore.
+func checkConfig(broker MinimalBroker, c config) error {
+	if c.secretStore != nil {
+		if err := secrets.CheckNamespace(c.secretNamespace); err != nil {
+			return fmt.Errorf("envoy: the namespace of the secret store is invalid: %w", err)
+		}
+	}
+
+	if multi, ok := broker.(MultiCredentialer); ok && c.authenticator == nil && c.bearerVerifier == nil {
+		for _, credentials := range multi.AllCredentials() {
+			if credentials.PasswordHash == "" {
+				continue
+			}
+			if err := middleware.CheckPasswordHash(credentials.PasswordHash); err != nil {
+				return fmt.Errorf("envoy: the password hash of %q cannot be verified: %w", credentials.Username, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkOperations returns an error naming the bindable services of the
+// catalog when the broker does not support binding and unbinding.
+func checkOperations(broker MinimalBroker, catalog domain.Catalog) error {
+	_, binds := broker.(Binder)
+	_, unbinds := broker.(Unbinder)
+	if binds && unbinds {
+		return nil
+	}
+
+	var bindable []string
+	for _, service := range catalog.Services {
+		if service.Bindable {
+			bindable = append(bindable, fmt.Sprintf("%q", service.Name))
+		}
+	}
+
+	if len(bindable) == 0 {
+		return nil
+	}
+
+	return domain.InvalidCatalogError(fmt.Sprintf("services %s are bindable, but the broker does not implement both Binder and Unbinder", strings.Join(bindable, ", ")))
+}
+
+var errShuttingDown = errors.New("envoy: the server is shutting down")
+
+// chain wraps the handler in the given middleware, the first of which
+// sees requests first.
+func chain(handler http.Handler, middleware []Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
+
+func authorizer(policy CertificatePolicy, operation string) func(*x509.Certificate) bool {
+	return func(certificate *x509.Certificate) bool {
+		return policy.Allows(certificate, operation)
+	}
+}
+
+// syncDeprovisioner adapts a Deprovisioner to AsyncDeprovisioner, always
+// deprovisioning synchronously.
+type syncDeprovisioner struct {
+	Deprovisioner
+}
+
+func (d syncDeprovisioner) DeprovisionAsync(request domain.DeprovisionRequest) (domain.DeprovisionResponse, error) {
+	return domain.DeprovisionResponse{}, d.Deprovisioner.Deprovision(request)
+}
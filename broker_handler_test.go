@@ -1,19 +1,49 @@
 package envoy_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pivotal-cf-experimental/envoy"
+	"github.com/pivotal-cf-experimental/envoy/bearer"
 	"github.com/pivotal-cf-experimental/envoy/domain"
 	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
 	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"github.com/pivotal-cf-experimental/envoy/metrics"
+	"github.com/pivotal-cf-experimental/envoy/redact"
+	"github.com/pivotal-cf-experimental/envoy/secrets"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
-type TestBroker struct{}
+type TestBroker struct {
+	BindCredentials    domain.BindingCredentials
+	ProvisionError     error
+	ProvisionContext   context.Context
+	ProvisionRequestID string
+	DeprovisionError   error
+}
 
 func NewTestBroker() *TestBroker {
 	return &TestBroker{}
@@ -24,11 +54,19 @@ func (broker TestBroker) Credentials() (string, string) {
 }
 
 func (broker *TestBroker) Provision(instance domain.ProvisionRequest) (domain.ProvisionResponse, error) {
-	return domain.ProvisionResponse{}, nil
+	if instance.InstanceID == "panic" {
+		panic("BANG!")
+	}
+
+	broker.ProvisionContext = instance.Context()
+	broker.ProvisionRequestID = instance.RequestID
+	return domain.ProvisionResponse{}, broker.ProvisionError
 }
 
 func (broker *TestBroker) Bind(binding domain.BindRequest) (domain.BindResponse, error) {
-	return domain.BindResponse{}, nil
+	return domain.BindResponse{
+		Credentials: broker.BindCredentials,
+	}, nil
 }
 
 func (broker *TestBroker) Unbind(unbinding domain.UnbindRequest) error {
@@ -36,13 +74,131 @@ func (broker *TestBroker) Unbind(unbinding domain.UnbindRequest) error {
 }
 
 func (broker *TestBroker) Deprovision(deprovision domain.DeprovisionRequest) error {
-	return nil
+	return broker.DeprovisionError
 }
 
 func (b TestBroker) Catalog() domain.Catalog {
 	return domain.Catalog{}
 }
 
+// authenticator unwraps the middleware wrapping every route, down to the
+// Authenticator.
+func authenticator(handler http.Handler) middleware.Authenticator {
+	for {
+		switch h := handler.(type) {
+		case middleware.Authenticator:
+			return h
+		case middleware.RequestID:
+			handler = h.Handler
+		case middleware.Tracer:
+			handler = h.Handler
+		case middleware.Logger:
+			handler = h.Handler
+		case middleware.Metrics:
+			handler = h.Handler
+		case middleware.Recoverer:
+			handler = h.Handler
+		case middleware.CertificateAuthorizer:
+			handler = h.Handler
+		case middleware.Lockout:
+			handler = h.Handler
+		case middleware.RateLimiter:
+			handler = h.Handler
+		case middleware.Drainer:
+			handler = h.Handler
+		default:
+			Fail(fmt.Sprintf("unexpected %T wrapping the route", handler))
+			return middleware.Authenticator{}
+		}
+	}
+}
+
+type RotatingBroker struct {
+	*TestBroker
+}
+
+func (broker RotatingBroker) AllCredentials() []domain.Credentials {
+	return []domain.Credentials{
+		{Username: "username", Password: "password"},
+		{Username: "rotated", Password: "pass:word"},
+	}
+}
+
+var _ envoy.MultiCredentialer = RotatingBroker{}
+
+type HashingBroker struct {
+	*TestBroker
+	PasswordHash string
+}
+
+func (broker HashingBroker) AllCredentials() []domain.Credentials {
+	return []domain.Credentials{{Username: "username", PasswordHash: broker.PasswordHash}}
+}
+
+type CheckedBroker struct {
+	*TestBroker
+	HealthError error
+}
+
+func (broker CheckedBroker) Catalog() domain.Catalog {
+	return domain.Catalog{
+		Services: []domain.Service{{
+			ID:          "service-id",
+			Name:        "my-service",
+			Description: "A service",
+			Plans:       []domain.Plan{{ID: "plan-id", Name: "small", Description: "A small plan"}},
+		}},
+	}
+}
+
+func (broker CheckedBroker) CheckHealth(ctx context.Context) error {
+	return broker.HealthError
+}
+
+var _ envoy.HealthChecker = CheckedBroker{}
+
+type ProvisionOnlyBroker struct {
+	Bindable bool
+}
+
+func (broker ProvisionOnlyBroker) Credentials() (string, string) {
+	return "username", "password"
+}
+
+func (broker ProvisionOnlyBroker) Catalog() domain.Catalog {
+	return domain.Catalog{
+		Services: []domain.Service{{
+			ID:          "service-id",
+			Name:        "my-service",
+			Description: "A service",
+			Bindable:    broker.Bindable,
+			Plans:       []domain.Plan{{ID: "plan-id", Name: "small", Description: "A small plan"}},
+		}},
+	}
+}
+
+func (broker ProvisionOnlyBroker) Provision(domain.ProvisionRequest) (domain.ProvisionResponse, error) {
+	return domain.ProvisionResponse{}, nil
+}
+
+type AsyncBroker struct {
+	ProvisionOnlyBroker
+	LastOperationRequest domain.LastOperationRequest
+	State                domain.LastOperationState
+}
+
+func (broker *AsyncBroker) DeprovisionAsync(request domain.DeprovisionRequest) (domain.DeprovisionResponse, error) {
+	return domain.DeprovisionResponse{Async: request.AcceptsIncomplete, Operation: "deprovision-1"}, nil
+}
+
+func (broker *AsyncBroker) LastOperation(request domain.LastOperationRequest) (domain.LastOperationResponse, error) {
+	broker.LastOperationRequest = request
+	if broker.State == "" {
+		return domain.LastOperationResponse{State: domain.Succeeded}, nil
+	}
+	return domain.LastOperationResponse{State: broker.State}, nil
+}
+
 var _ = Describe("BrokerHandler", func() {
 	var testBroker *TestBroker
 	var router *mux.Router
@@ -61,8 +217,8 @@ var _ = Describe("BrokerHandler", func() {
 
 			var match mux.RouteMatch
 			Expect(router.Match(request, &match)).To(BeTrue())
-			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.Authenticator{}))
-			auth := match.Handler.(middleware.Authenticator)
+			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.RequestID{}))
+			auth := authenticator(match.Handler)
 			Expect(auth.Handler).To(BeAssignableToTypeOf(handlers.CatalogHandler{}))
 		})
 
@@ -86,8 +242,8 @@ var _ = Describe("BrokerHandler", func() {
 
 			var match mux.RouteMatch
 			Expect(router.Match(request, &match)).To(BeTrue())
-			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.Authenticator{}))
-			auth := match.Handler.(middleware.Authenticator)
+			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.RequestID{}))
+			auth := authenticator(match.Handler)
 			Expect(auth.Handler).To(BeAssignableToTypeOf(handlers.ProvisionHandler{}))
 		})
 
@@ -111,8 +267,8 @@ var _ = Describe("BrokerHandler", func() {
 
 			var match mux.RouteMatch
 			Expect(router.Match(request, &match)).To(BeTrue())
-			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.Authenticator{}))
-			auth := match.Handler.(middleware.Authenticator)
+			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.RequestID{}))
+			auth := authenticator(match.Handler)
 			Expect(auth.Handler).To(BeAssignableToTypeOf(handlers.BindHandler{}))
 		})
 
@@ -136,8 +292,8 @@ var _ = Describe("BrokerHandler", func() {
 
 			var match mux.RouteMatch
 			Expect(router.Match(request, &match)).To(BeTrue())
-			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.Authenticator{}))
-			auth := match.Handler.(middleware.Authenticator)
+			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.RequestID{}))
+			auth := authenticator(match.Handler)
 			Expect(auth.Handler).To(BeAssignableToTypeOf(handlers.UnbindHandler{}))
 		})
 
@@ -161,8 +317,8 @@ var _ = Describe("BrokerHandler", func() {
 
 			var match mux.RouteMatch
 			Expect(router.Match(request, &match)).To(BeTrue())
-			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.Authenticator{}))
-			auth := match.Handler.(middleware.Authenticator)
+			Expect(match.Handler).To(BeAssignableToTypeOf(middleware.RequestID{}))
+			auth := authenticator(match.Handler)
 			Expect(auth.Handler).To(BeAssignableToTypeOf(handlers.DeprovisionHandler{}))
 		})
 
@@ -176,4 +332,961 @@ var _ = Describe("BrokerHandler", func() {
 			Expect(router.Match(request, &match)).To(BeFalse())
 		})
 	})
+
+	Context("when a secret store is configured", func() {
+		var store *secrets.MemoryStore
+
+		BeforeEach(func() {
+			store = secrets.NewMemoryStore()
+			testBroker.BindCredentials = domain.BindingCredentials{"password": "secret"}
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithSecretStore(store, "test-broker")).(*mux.Router)
+		})
+
+		It("refuses a namespace under which no secret can be stored", func() {
+			Expect(func() { envoy.NewBrokerHandler(testBroker, envoy.WithSecretStore(store, "")) }).To(PanicWith(MatchError(ContainSubstring("envoy: the namespace of the secret store is invalid"))))
+		})
+
+		It("returns a reference to the stored credentials, and deletes them on unbind", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("PUT", "/v2/service_instances/banana/service_bindings/panic", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusCreated))
+			Expect(writer.Body.String()).To(MatchJSON(`{
+				"credentials": {
+					"credhub-ref": "/c/test-broker/service-id/panic/credentials"
+				}
+			}`))
+			Expect(store.Get("/c/test-broker/service-id/panic/credentials")).To(Equal(domain.BindingCredentials{"password": "secret"}))
+
+			writer = httptest.NewRecorder()
+			request, err = http.NewRequest("DELETE", "/v2/service_instances/banana/service_bindings/panic?service_id=service-id&plan_id=plan-id", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			_, err = store.Get("/c/test-broker/service-id/panic/credentials")
+			Expect(err).To(Equal(secrets.NotFoundError("/c/test-broker/service-id/panic/credentials")))
+		})
+	})
+
+	Context("when a logger is configured", func() {
+		var buffer *bytes.Buffer
+
+		BeforeEach(func() {
+			buffer = bytes.NewBuffer([]byte{})
+			logger := slog.New(slog.NewJSONHandler(buffer, nil))
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithLogger(logger)).(*mux.Router)
+		})
+
+		It("logs requests, including those failing authentication", func() {
+			request, err := http.NewRequest("GET", "/v2/catalog", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			var match mux.RouteMatch
+			Expect(router.Match(request, &match)).To(BeTrue())
+			requestID := match.Handler.(middleware.RequestID)
+			Expect(requestID.Handler).To(BeAssignableToTypeOf(middleware.Logger{}))
+			logger := requestID.Handler.(middleware.Logger)
+			Expect(logger.Handler).To(BeAssignableToTypeOf(middleware.Recoverer{}))
+
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			Expect(buffer.String()).To(ContainSubstring(`"route":"/v2/catalog"`))
+			Expect(buffer.String()).To(ContainSubstring(`"status":401`))
+		})
+	})
+
+	Context("when the broker returns an error containing secrets", func() {
+		var request *http.Request
+
+		BeforeEach(func() {
+			var err error
+			testBroker.ProvisionError = errors.New("dial postgres://admin:hunter2@db, token=abc123 failed")
+			request, err = http.NewRequest("PUT", "/v2/service_instances/banana", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id",
+				"organization_guid": "org-guid",
+				"space_guid": "space-guid"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("X-Request-Id", "request-id")
+		})
+
+		It("masks them in the error description", func() {
+			writer := httptest.NewRecorder()
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusInternalServerError))
+			Expect(writer.Body.String()).To(MatchJSON(`{
+				"description": "dial postgres://admin:[REDACTED]@db, token=[REDACTED] failed (request ID: request-id)"
+			}`))
+		})
+
+		It("masks user-declared fields", func() {
+			testBroker.ProvisionError = errors.New("tenant_key: 1234 rejected")
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithRedactor(redact.New("tenant_key"))).(*mux.Router)
+			writer := httptest.NewRecorder()
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Body.String()).To(MatchJSON(`{"description": "tenant_key: [REDACTED] rejected (request ID: request-id)"}`))
+		})
+
+		It("keeps the type of wrapped domain errors", func() {
+			testBroker.DeprovisionError = fmt.Errorf("token=abc123 rejected: %w", domain.ServiceInstanceNotFoundError("banana is gone"))
+			registry := metrics.NewRegistry()
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithMetrics(registry)).(*mux.Router)
+			request, err := http.NewRequest("DELETE", "/v2/service_instances/banana?service_id=service-id&plan_id=plan-id", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			writer := httptest.NewRecorder()
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusGone))
+			Expect(writer.Body.String()).To(MatchJSON(`{}`))
+
+			writer = httptest.NewRecorder()
+			registry.ServeHTTP(writer, nil)
+			Expect(writer.Body.String()).To(ContainSubstring(`envoy_errors_total{operation="deprovision",error_type="ServiceInstanceNotFound"} 1`))
+		})
+	})
+
+	Context("when metrics are configured", func() {
+		BeforeEach(func() {
+			testBroker.ProvisionError = domain.ServiceInstanceAlreadyExistsError("exists")
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithMetrics(metrics.NewRegistry())).(*mux.Router)
+		})
+
+		It("serves the recorded metrics on an authenticated route", func() {
+			request, err := http.NewRequest("PUT", "/v2/service_instances/banana", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id",
+				"organization_guid": "org-guid",
+				"space_guid": "space-guid"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			writer := httptest.NewRecorder()
+			request, err = http.NewRequest("GET", "/metrics", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(writer, request)
+			Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+
+			writer = httptest.NewRecorder()
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(writer.Body.String()).To(ContainSubstring(`envoy_requests_total{operation="provision",code="409"} 1`))
+			Expect(writer.Body.String()).To(ContainSubstring(`envoy_requests_total{operation="metrics",code="401"} 1`))
+			Expect(writer.Body.String()).To(ContainSubstring(`envoy_errors_total{operation="provision",error_type="ServiceInstanceAlreadyExists"} 1`))
+			Expect(writer.Body.String()).To(ContainSubstring(`envoy_operation_duration_seconds_count{operation="provision",service_id="service-id",plan_id="plan-id"} 1`))
+		})
+	})
+
+	It("tracks asynchronous operations in flight until they are reported as finished", func() {
+		asyncBroker := &AsyncBroker{State: domain.InProgress}
+		router = envoy.NewBrokerHandler(asyncBroker, envoy.WithMetrics(metrics.NewRegistry())).(*mux.Router)
+
+		serve := func(method, path string) *httptest.ResponseRecorder {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest(method, path, nil)
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+			return writer
+		}
+		inFlight := func() string {
+			return serve("GET", "/metrics").Body.String()
+		}
+
+		Expect(serve("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p&accepts_incomplete=true").Code).To(Equal(http.StatusAccepted))
+		Expect(inFlight()).To(ContainSubstring(`envoy_async_operations_in_flight{operation="deprovision"} 1` + "\n"))
+
+		Expect(serve("GET", "/v2/service_instances/banana/last_operation").Code).To(Equal(http.StatusOK))
+		Expect(inFlight()).To(ContainSubstring(`envoy_async_operations_in_flight{operation="deprovision"} 1` + "\n"))
+
+		asyncBroker.State = domain.Succeeded
+		Expect(serve("GET", "/v2/service_instances/banana/last_operation").Code).To(Equal(http.StatusOK))
+		Expect(serve("GET", "/v2/service_instances/banana/last_operation").Code).To(Equal(http.StatusOK))
+		Expect(inFlight()).To(ContainSubstring(`envoy_async_operations_in_flight{operation="deprovision"} 0` + "\n"))
+	})
+
+	It("does not serve metrics unless configured", func() {
+		request, err := http.NewRequest("GET", "/metrics", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		var match mux.RouteMatch
+		Expect(router.Match(request, &match)).To(BeFalse())
+	})
+
+	Context("when a tracer provider is configured", func() {
+		var exporter *tracetest.InMemoryExporter
+
+		BeforeEach(func() {
+			exporter = tracetest.NewInMemoryExporter()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithTracerProvider(provider)).(*mux.Router)
+		})
+
+		It("traces the request through authentication, parsing and the broker call", func() {
+			request, err := http.NewRequest("PUT", "/v2/service_instances/banana", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id",
+				"organization_guid": "org-guid",
+				"space_guid": "space-guid"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			spans := exporter.GetSpans()
+			names := []string{}
+			for _, span := range spans {
+				Expect(span.SpanContext.TraceID().String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+				names = append(names, span.Name)
+			}
+			Expect(names).To(Equal([]string{
+				"Authenticator",
+				"ProvisionHandler.Parse",
+				"Broker.Provision",
+				"PUT /v2/service_instances/{instance_id}",
+			}))
+
+			brokerSpan := trace.SpanContextFromContext(testBroker.ProvisionContext)
+			Expect(brokerSpan).To(Equal(spans[2].SpanContext))
+		})
+	})
+
+	Context("when the request is identified", func() {
+		It("hands the request ID to the broker, and echoes it in the response", func() {
+			testBroker.ProvisionError = errors.New("BANG!")
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("PUT", "/v2/service_instances/banana", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id",
+				"organization_guid": "org-guid",
+				"space_guid": "space-guid"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("X-Broker-API-Request-Identity", "e26cea0c-8d8b-4c51-a0d4-98e8f2b8c3b5")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(testBroker.ProvisionRequestID).To(Equal("e26cea0c-8d8b-4c51-a0d4-98e8f2b8c3b5"))
+			Expect(writer.Header().Get("X-Broker-API-Request-Identity")).To(Equal("e26cea0c-8d8b-4c51-a0d4-98e8f2b8c3b5"))
+			Expect(writer.Header().Get("X-Request-Id")).To(Equal("e26cea0c-8d8b-4c51-a0d4-98e8f2b8c3b5"))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description": "BANG! (request ID: e26cea0c-8d8b-4c51-a0d4-98e8f2b8c3b5)"}`))
+		})
+
+		It("generates a request ID when none is given", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", "/v2/catalog", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Header().Get("X-Request-Id")).To(HaveLen(36))
+		})
+	})
+
+	Context("when the broker panics", func() {
+		var recovered interface{}
+
+		BeforeEach(func() {
+			hook := func(req *http.Request, value interface{}, stack []byte) {
+				recovered = value
+			}
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithPanicHook(hook), envoy.WithLogger(slog.New(slog.NewJSONHandler(GinkgoWriter, nil)))).(*mux.Router)
+		})
+
+		It("responds with a 500, and reports the panic", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("PUT", "/v2/service_instances/panic", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id",
+				"organization_guid": "org-guid",
+				"space_guid": "space-guid"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("X-Request-Id", "request-id")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusInternalServerError))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description": "an unexpected error occurred (request ID: request-id)"}`))
+			Expect(recovered).To(Equal("BANG!"))
+		})
+	})
+
+	Context("when body limits and strict decoding are configured", func() {
+		BeforeEach(func() {
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithMaxBodySize(64), envoy.WithStrictDecoding()).(*mux.Router)
+		})
+
+		It("responds to oversized bodies with a 413", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("PUT", "/v2/service_instances/banana", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id",
+				"organization_guid": "org-guid",
+				"space_guid": "space-guid"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("X-Request-Id", "request-id")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description": "request body must not exceed 64 bytes (request ID: request-id)"}`))
+		})
+
+		It("rejects unknown fields", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("PUT", "/v2/service_instances/banana/service_bindings/apple", strings.NewReader(`{"service_id":"s","plan_id":"p","extra":1}`))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("X-Request-Id", "request-id")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description": "unknown field \"extra\" (request ID: request-id)"}`))
+		})
+	})
+
+	Context("when the request path has unexpected segments", func() {
+		It("does not route extra segments or trailing slashes", func() {
+			for _, path := range []string{"/v2/service_instances/a/b", "/v2/service_instances/banana/"} {
+				writer := httptest.NewRecorder()
+				request, err := http.NewRequest("DELETE", path+"?service_id=s&plan_id=p", nil)
+				if err != nil {
+					panic(err)
+				}
+				request.SetBasicAuth("username", "password")
+
+				router.ServeHTTP(writer, request)
+
+				Expect(writer.Code).To(Equal(http.StatusNotFound), path)
+			}
+		})
+
+		It("rejects IDs containing encoded slashes", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("DELETE", "/v2/service_instances/a%2Fb?service_id=s&plan_id=p", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("X-Request-Id", "request-id")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description": "path parameter \"instance_id\" has an invalid value \"a/b\" (request ID: request-id)"}`))
+		})
+	})
+
+	Context("when IDs must be GUIDs", func() {
+		BeforeEach(func() {
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithIDPattern(envoy.GUIDPattern)).(*mux.Router)
+		})
+
+		It("rejects other IDs", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("when the broker accepts several credential pairs", func() {
+		BeforeEach(func() {
+			router = envoy.NewBrokerHandler(RotatingBroker{testBroker}).(*mux.Router)
+		})
+
+		It("accepts requests authenticated with any of them", func() {
+			for _, credentials := range []domain.Credentials{{Username: "username", Password: "password"}, {Username: "rotated", Password: "pass:word"}} {
+				writer := httptest.NewRecorder()
+				request, err := http.NewRequest("GET", "/v2/catalog", nil)
+				if err != nil {
+					panic(err)
+				}
+				request.SetBasicAuth(credentials.Username, credentials.Password)
+
+				router.ServeHTTP(writer, request)
+
+				Expect(writer.Code).To(Equal(http.StatusOK))
+			}
+		})
+	})
+
+	It("refuses password hashes that cannot be verified", func() {
+		broker := HashingBroker{testBroker, "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5"}
+
+		Expect(func() { envoy.NewBrokerHandler(broker) }).To(PanicWith(MatchError(ContainSubstring(`the password hash of "username" cannot be verified`))))
+	})
+
+	Context("when bearer authentication is configured", func() {
+		var key *ecdsa.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			keys, err := bearer.ParseKeySet([]byte(fmt.Sprintf(`{"keys": [{"kty": "EC", "kid": "key", "crv": "P-256", "x": %q, "y": %q}]}`,
+				base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+				base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))))))
+			Expect(err).NotTo(HaveOccurred())
+
+			verifier := bearer.NewVerifier(keys, bearer.Config{RequiredScopes: []string{"broker.admin"}})
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithBearerAuth(verifier)).(*mux.Router)
+		})
+
+		token := func(claims string) string {
+			signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"key"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+			sum := sha256.Sum256([]byte(signed))
+			r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+			Expect(err).NotTo(HaveOccurred())
+
+			return signed + "." + base64.RawURLEncoding.EncodeToString(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...))
+		}
+
+		It("hands the claims of the token to the broker", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("PUT", "/v2/service_instances/banana", strings.NewReader(`{
+				"service_id": "service-id",
+				"plan_id": "plan-id",
+				"organization_guid": "org-guid",
+				"space_guid": "space-guid"
+			}`))
+			if err != nil {
+				panic(err)
+			}
+			request.Header.Set("Authorization", "Bearer "+token(fmt.Sprintf(`{"sub":"admin","scope":["broker.admin"],"exp":%d}`, time.Now().Add(time.Hour).Unix())))
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusCreated))
+			claims, ok := bearer.FromContext(testBroker.ProvisionContext)
+			Expect(ok).To(BeTrue())
+			Expect(claims.Subject).To(Equal("admin"))
+		})
+
+		It("rejects Basic Auth credentials", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", "/v2/catalog", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+			Expect(writer.Header().Get("WWW-Authenticate")).To(Equal(`Bearer realm="service broker"`))
+		})
+
+		It("rejects tokens lacking the required scope", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", "/v2/catalog", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.Header.Set("Authorization", "Bearer "+token(fmt.Sprintf(`{"sub":"admin","scope":"openid","exp":%d}`, time.Now().Add(time.Hour).Unix())))
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("when a lockout is configured", func() {
+		BeforeEach(func() {
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithLockout(2, time.Minute)).(*mux.Router)
+		})
+
+		It("locks clients out after repeated authentication failures", func() {
+			serve := func(password string) *httptest.ResponseRecorder {
+				writer := httptest.NewRecorder()
+				request, err := http.NewRequest("GET", "/v2/catalog", nil)
+				if err != nil {
+					panic(err)
+				}
+				request.RemoteAddr = "10.0.0.1:1234"
+				request.SetBasicAuth("username", password)
+
+				router.ServeHTTP(writer, request)
+				return writer
+			}
+
+			Expect(serve("guess").Code).To(Equal(http.StatusUnauthorized))
+			Expect(serve("another-guess").Code).To(Equal(http.StatusUnauthorized))
+			Expect(serve("password").Code).To(Equal(http.StatusTooManyRequests))
+		})
+	})
+
+	It("treats zero and negative throttling limits as disabled", func() {
+		for _, option := range []envoy.Option{
+			envoy.WithRateLimit(1, 0),
+			envoy.WithRateLimit(0, 5),
+			envoy.WithRateLimit(-1, -1),
+			envoy.WithConcurrencyLimit("catalog", 0),
+			envoy.WithConcurrencyLimit("catalog", -1),
+			envoy.WithLockout(0, time.Minute),
+			envoy.WithLockout(-1, time.Minute),
+			envoy.WithLockout(1, 0),
+		} {
+			router = envoy.NewBrokerHandler(testBroker, option).(*mux.Router)
+
+			for _, password := range []string{"guess", "password", "password"} {
+				writer := httptest.NewRecorder()
+				request, err := http.NewRequest("GET", "/v2/catalog", nil)
+				if err != nil {
+					panic(err)
+				}
+				request.RemoteAddr = "10.0.0.1:1234"
+				request.SetBasicAuth("username", password)
+
+				router.ServeHTTP(writer, request)
+				if password == "password" {
+					Expect(writer.Code).To(Equal(http.StatusOK))
+				}
+			}
+		}
+	})
+
+	Context("when a lockout and a certificate policy are configured", func() {
+		BeforeEach(func() {
+			router = envoy.NewBrokerHandler(testBroker,
+				envoy.WithLockout(2, time.Minute),
+				envoy.WithCertificatePolicy(envoy.CertificatePolicy{{CommonName: "cloud-controller", Operations: []string{"*"}}}),
+			).(*mux.Router)
+		})
+
+		It("does not let responses given before authentication reset the failures", func() {
+			serve := func(withCertificate bool, password string) *httptest.ResponseRecorder {
+				writer := httptest.NewRecorder()
+				request, err := http.NewRequest("GET", "/v2/catalog", nil)
+				if err != nil {
+					panic(err)
+				}
+				request.RemoteAddr = "10.0.0.1:1234"
+				request.SetBasicAuth("username", password)
+				if withCertificate {
+					request.TLS = &tls.ConnectionState{
+						VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "cloud-controller"}}}},
+					}
+				}
+
+				router.ServeHTTP(writer, request)
+				return writer
+			}
+
+			Expect(serve(true, "guess").Code).To(Equal(http.StatusUnauthorized))
+			Expect(serve(false, "password").Code).To(Equal(http.StatusForbidden))
+			Expect(serve(true, "another-guess").Code).To(Equal(http.StatusUnauthorized))
+			Expect(serve(true, "password").Code).To(Equal(http.StatusTooManyRequests))
+		})
+	})
+
+	Context("when a lockout is configured behind a trusted proxy", func() {
+		BeforeEach(func() {
+			_, proxies, err := net.ParseCIDR("10.0.0.0/24")
+			Expect(err).NotTo(HaveOccurred())
+			router = envoy.NewBrokerHandler(testBroker,
+				envoy.WithLockout(1, time.Minute),
+				envoy.WithClientKey(envoy.ForwardedClientKey(proxies)),
+			).(*mux.Router)
+		})
+
+		It("locks out only the client that failed to authenticate", func() {
+			serve := func(forwardedFor, password string) *httptest.ResponseRecorder {
+				writer := httptest.NewRecorder()
+				request, err := http.NewRequest("GET", "/v2/catalog", nil)
+				if err != nil {
+					panic(err)
+				}
+				request.RemoteAddr = "10.0.0.1:1234"
+				request.Header.Set("X-Forwarded-For", forwardedFor)
+				request.SetBasicAuth("username", password)
+
+				router.ServeHTTP(writer, request)
+				return writer
+			}
+
+			Expect(serve("203.0.113.7", "guess").Code).To(Equal(http.StatusUnauthorized))
+			Expect(serve("203.0.113.7", "password").Code).To(Equal(http.StatusTooManyRequests))
+			Expect(serve("198.51.100.1", "password").Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when a rate limit is configured", func() {
+		BeforeEach(func() {
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithRateLimit(1, 2)).(*mux.Router)
+		})
+
+		It("limits requests across routes", func() {
+			var codes []int
+			for _, path := range []string{"/v2/catalog", "/v2/catalog", "/v2/service_instances/banana?service_id=s&plan_id=p"} {
+				writer := httptest.NewRecorder()
+				method := "GET"
+				if strings.Contains(path, "banana") {
+					method = "DELETE"
+				}
+				request, err := http.NewRequest(method, path, nil)
+				if err != nil {
+					panic(err)
+				}
+				request.RemoteAddr = "10.0.0.1:1234"
+				request.SetBasicAuth("username", "password")
+
+				router.ServeHTTP(writer, request)
+				codes = append(codes, writer.Code)
+			}
+
+			Expect(codes).To(Equal([]int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}))
+		})
+	})
+
+	Context("when health endpoints are configured", func() {
+		var checkedBroker *CheckedBroker
+
+		BeforeEach(func() {
+			checkedBroker = &CheckedBroker{TestBroker: testBroker}
+			router = envoy.NewBrokerHandler(checkedBroker, envoy.WithHealthEndpoints(), envoy.WithRateLimit(1, 1)).(*mux.Router)
+		})
+
+		probe := func(path string) *httptest.ResponseRecorder {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", path, nil)
+			if err != nil {
+				panic(err)
+			}
+			request.RemoteAddr = "10.0.0.1:1234"
+
+			router.ServeHTTP(writer, request)
+			return writer
+		}
+
+		It("serves the probes without authentication or rate limiting", func() {
+			for i := 0; i < 3; i++ {
+				writer := probe("/healthz")
+				Expect(writer.Code).To(Equal(http.StatusOK))
+				Expect(writer.Body.String()).To(MatchJSON(`{"status":"ok"}`))
+			}
+
+			writer := probe("/readyz")
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(writer.Body.String()).To(MatchJSON(`{"status":"ok","checks":{"catalog":"ok","broker":"ok"}}`))
+		})
+
+		It("is not ready when the broker is unhealthy", func() {
+			checkedBroker.HealthError = errors.New("database unreachable")
+
+			writer := probe("/readyz")
+			Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(writer.Body.String()).To(MatchJSON(`{"status":"unavailable","checks":{"catalog":"ok","broker":"failing"}}`))
+		})
+
+		It("is not ready when the catalog is invalid", func() {
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithHealthEndpoints()).(*mux.Router)
+
+			writer := probe("/readyz")
+			Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(writer.Body.String()).To(MatchJSON(`{"status":"unavailable","checks":{"catalog":"failing"}}`))
+		})
+
+		It("serves no probes unless configured", func() {
+			router = envoy.NewBrokerHandler(testBroker).(*mux.Router)
+
+			Expect(probe("/healthz").Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when a maintenance mode is configured", func() {
+		var mode *envoy.MaintenanceMode
+
+		BeforeEach(func() {
+			mode = envoy.NewMaintenanceMode()
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithMaintenanceMode(mode)).(*mux.Router)
+		})
+
+		serve := func(method, path, body string) *httptest.ResponseRecorder {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest(method, path, strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+			return writer
+		}
+
+		provision := func() *httptest.ResponseRecorder {
+			return serve("PUT", "/v2/service_instances/banana", `{"service_id":"s","plan_id":"p","organization_guid":"o","space_guid":"s"}`)
+		}
+
+		It("refuses mutating requests while enabled, but still serves the catalog", func() {
+			Expect(provision().Code).To(Equal(http.StatusCreated))
+
+			mode.Enable("upgrading the database", 10*time.Minute)
+
+			writer := provision()
+			Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(writer.Header().Get("Retry-After")).To(Equal("600"))
+			Expect(writer.Body.String()).To(ContainSubstring("upgrading the database"))
+			Expect(serve("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p", "").Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(serve("GET", "/v2/catalog", "").Code).To(Equal(http.StatusOK))
+		})
+
+		It("is toggled through the authenticated admin endpoint", func() {
+			writer := serve("PUT", "/admin/maintenance", `{"enabled":true,"description":"upgrading","retry_after":60}`)
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(mode.Enabled()).To(BeTrue())
+			Expect(provision().Code).To(Equal(http.StatusServiceUnavailable))
+
+			writer = serve("PUT", "/admin/maintenance", `{"enabled":false}`)
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(provision().Code).To(Equal(http.StatusCreated))
+
+			writer = httptest.NewRecorder()
+			request, err := http.NewRequest("PUT", "/admin/maintenance", strings.NewReader(`{"enabled":true}`))
+			if err != nil {
+				panic(err)
+			}
+			router.ServeHTTP(writer, request)
+			Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+			Expect(mode.Enabled()).To(BeFalse())
+		})
+	})
+
+	Context("when middleware and routes are configured", func() {
+		var calls []string
+
+		record := func(name string) envoy.Middleware {
+			return func(handler http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					calls = append(calls, name)
+					handler.ServeHTTP(w, req)
+				})
+			}
+		}
+
+		serve := func(method, path string, authenticated bool) *httptest.ResponseRecorder {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest(method, path, nil)
+			if err != nil {
+				panic(err)
+			}
+			if authenticated {
+				request.SetBasicAuth("username", "password")
+			}
+
+			router.ServeHTTP(writer, request)
+			return writer
+		}
+
+		BeforeEach(func() {
+			calls = nil
+		})
+
+		It("runs global middleware before authentication and route middleware after it", func() {
+			router = envoy.NewBrokerHandler(testBroker,
+				envoy.WithMiddleware(record("first"), record("second")),
+				envoy.WithRouteMiddleware("catalog", record("catalog")),
+			).(*mux.Router)
+
+			Expect(serve("GET", "/v2/catalog", true).Code).To(Equal(http.StatusOK))
+			Expect(calls).To(Equal([]string{"first", "second", "catalog"}))
+
+			calls = nil
+			Expect(serve("GET", "/v2/catalog", false).Code).To(Equal(http.StatusUnauthorized))
+			Expect(calls).To(Equal([]string{"first", "second"}))
+
+			calls = nil
+			Expect(serve("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p", true).Code).To(Equal(http.StatusOK))
+			Expect(calls).To(Equal([]string{"first", "second"}))
+		})
+
+		It("replaces the authenticator", func() {
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithAuthenticator(func(handler http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if req.Header.Get("X-Api-Key") != "secret" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					handler.ServeHTTP(w, req)
+				})
+			})).(*mux.Router)
+
+			Expect(serve("GET", "/v2/catalog", true).Code).To(Equal(http.StatusUnauthorized))
+
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", "/v2/catalog", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.Header.Set("X-Api-Key", "secret")
+			router.ServeHTTP(writer, request)
+			Expect(writer.Code).To(Equal(http.StatusOK))
+		})
+
+		It("mounts every route under the path prefix", func() {
+			router = envoy.NewBrokerHandler(testBroker, envoy.WithPathPrefix("/broker/"), envoy.WithHealthEndpoints()).(*mux.Router)
+
+			Expect(serve("GET", "/broker/v2/catalog", true).Code).To(Equal(http.StatusOK))
+			Expect(serve("GET", "/broker/healthz", false).Code).To(Equal(http.StatusOK))
+			Expect(serve("GET", "/v2/catalog", true).Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("serves extra authenticated routes next to the service broker API", func() {
+			backups := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte(mux.Vars(req)["instance_id"]))
+			})
+			router = envoy.NewBrokerHandler(testBroker,
+				envoy.WithRoute("backups", "GET", "/v2/service_instances/{instance_id}/backups", backups),
+				envoy.WithRouteMiddleware("backups", record("backups")),
+			).(*mux.Router)
+
+			writer := serve("GET", "/v2/service_instances/banana/backups", true)
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(writer.Body.String()).To(Equal("banana"))
+			Expect(calls).To(Equal([]string{"backups"}))
+			Expect(router.Get("backups")).NotTo(BeNil())
+
+			Expect(serve("GET", "/v2/service_instances/banana/backups", false).Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("when the broker only implements some operations", func() {
+		serve := func(method, path, body string) *httptest.ResponseRecorder {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest(method, path, strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(writer, request)
+			return writer
+		}
+
+		BeforeEach(func() {
+			router = envoy.NewBrokerHandler(ProvisionOnlyBroker{}, envoy.WithHealthEndpoints()).(*mux.Router)
+		})
+
+		It("serves the implemented operations", func() {
+			writer := serve("PUT", "/v2/service_instances/banana", `{"service_id":"s","plan_id":"p","organization_guid":"o","space_guid":"s"}`)
+			Expect(writer.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("answers the other operations with a 501", func() {
+			writer := serve("PUT", "/v2/service_instances/banana/service_bindings/apple", `{"service_id":"s","plan_id":"p","app_guid":"a"}`)
+			Expect(writer.Code).To(Equal(http.StatusNotImplemented))
+			Expect(writer.Body.String()).To(ContainSubstring("does not support the bind operation"))
+
+			Expect(serve("DELETE", "/v2/service_instances/banana/service_bindings/apple?service_id=s&plan_id=p", "").Code).To(Equal(http.StatusNotImplemented))
+			Expect(serve("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p", "").Code).To(Equal(http.StatusNotImplemented))
+			Expect(serve("GET", "/v2/service_instances/banana/last_operation", "").Code).To(Equal(http.StatusNotImplemented))
+		})
+
+		It("serves asynchronous deprovisioning and the last operation of brokers implementing them", func() {
+			asyncBroker := &AsyncBroker{}
+			router = envoy.NewBrokerHandler(asyncBroker).(*mux.Router)
+
+			writer := serve("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p&accepts_incomplete=true", "")
+			Expect(writer.Code).To(Equal(http.StatusAccepted))
+			Expect(writer.Body.String()).To(MatchJSON(`{"operation":"deprovision-1"}`))
+
+			writer = serve("GET", "/v2/service_instances/banana/last_operation?operation=deprovision-1", "")
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(writer.Body.String()).To(MatchJSON(`{"state":"succeeded"}`))
+			Expect(asyncBroker.LastOperationRequest.Operation).To(Equal("deprovision-1"))
+
+			Expect(serve("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p", "").Code).To(Equal(http.StatusOK))
+		})
+
+		It("still authenticates requests for the other operations", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("DELETE", "/v2/service_instances/banana?service_id=s&plan_id=p", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(writer, request)
+			Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("is ready when no service is bindable", func() {
+			Expect(serve("GET", "/readyz", "").Code).To(Equal(http.StatusOK))
+		})
+
+		It("is not ready when the catalog offers bindable services the broker cannot bind", func() {
+			router = envoy.NewBrokerHandler(ProvisionOnlyBroker{Bindable: true}, envoy.WithHealthEndpoints()).(*mux.Router)
+
+			writer := serve("GET", "/readyz", "")
+			Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(writer.Body.String()).To(MatchJSON(`{"status":"unavailable","checks":{"catalog":"failing"}}`))
+		})
+	})
 })
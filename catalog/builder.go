@@ -0,0 +1,294 @@
+package catalog
+
+import (
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
+
+// Builder builds a catalog fluently:
+//
+//	builder := catalog.NewBuilder("5a5f2b8e-8d5e-4b6f-9c3e-1f0d2a6b7c8d")
+//	postgres := builder.Service("postgres", "A PostgreSQL database").Tags("sql")
+//	postgres.Plan("small", "A small database")
+//	postgres.Plan("large", "A large database").Monthly(catalog.USD(99))
+//	built, err := builder.Build()
+//
+// Services and plans are given IDs derived from their names, as version 5
+// UUIDs in the namespace of the builder, so that they are the same every
+// time the catalog is built. Services are bindable, and plans are free
+// unless they have a cost, by default.
+type Builder struct {
+	namespace string
+	services  []*ServiceBuilder
+}
+
+// ServiceBuilder builds a service of a catalog; see Builder.
+type ServiceBuilder struct {
+	service domain.Service
+	plans   []*PlanBuilder
+}
+
+// PlanBuilder builds a plan of a service; see Builder.
+type PlanBuilder struct {
+	plan domain.Plan
+	free *bool
+}
+
+// NewBuilder returns a Builder deriving IDs in the given namespace, a
+// UUID that should be unique to the broker.
+func NewBuilder(namespace string) *Builder {
+	return &Builder{
+		namespace: namespace,
+	}
+}
+
+// Service adds a service to the catalog.
+func (b *Builder) Service(name, description string) *ServiceBuilder {
+	service := &ServiceBuilder{
+		service: domain.Service{
+			Name:        name,
+			Description: description,
+			Bindable:    true,
+		},
+	}
+	b.services = append(b.services, service)
+
+	return service
+}
+
+// Build returns the catalog, or an InvalidCatalogError if it would be
+// rejected by Cloud Foundry. The returned catalog shares nothing with the
+// builder, so later changes to the builder do not affect it.
+func (b *Builder) Build() (domain.Catalog, error) {
+	namespace, err := parseUUID(b.namespace)
+	if err != nil {
+		return domain.Catalog{}, err
+	}
+
+	catalog := domain.Catalog{
+		Services: make([]domain.Service, len(b.services)),
+	}
+	for i, s := range b.services {
+		service := s.service
+		if service.ID == "" {
+			service.ID = uuidV5(namespace, service.Name).String()
+		}
+		service.Tags = copyStrings(service.Tags)
+		service.Requires = copyStrings(service.Requires)
+		if service.Metadata != nil {
+			metadata := *service.Metadata
+			service.Metadata = &metadata
+		}
+		if service.DashboardClient != nil {
+			client := *service.DashboardClient
+			service.DashboardClient = &client
+		}
+
+		service.Plans = make([]domain.Plan, len(s.plans))
+		for j, p := range s.plans {
+			service.Plans[j] = p.build(namespace, s.service.Name)
+		}
+
+		catalog.Services[i] = service
+	}
+
+	if err := catalog.Validate(); err != nil {
+		return domain.Catalog{}, err
+	}
+
+	return catalog, nil
+}
+
+// ID replaces the ID derived from the name of the service.
+func (s *ServiceBuilder) ID(id string) *ServiceBuilder {
+	s.service.ID = id
+	return s
+}
+
+// Bindable sets whether the service can be bound to applications.
+func (s *ServiceBuilder) Bindable(bindable bool) *ServiceBuilder {
+	s.service.Bindable = bindable
+	return s
+}
+
+// Tags adds tags to the service.
+func (s *ServiceBuilder) Tags(tags ...string) *ServiceBuilder {
+	s.service.Tags = append(s.service.Tags, tags...)
+	return s
+}
+
+// Requires adds permissions the service requires, such as "syslog_drain".
+func (s *ServiceBuilder) Requires(permissions ...string) *ServiceBuilder {
+	s.service.Requires = append(s.service.Requires, permissions...)
+	return s
+}
+
+// DisplayName sets the name of the service shown in user interfaces.
+func (s *ServiceBuilder) DisplayName(name string) *ServiceBuilder {
+	s.metadata().DisplayName = name
+	return s
+}
+
+// LongDescription sets the long description of the service shown in user
+// interfaces.
+func (s *ServiceBuilder) LongDescription(description string) *ServiceBuilder {
+	s.metadata().LongDescription = description
+	return s
+}
+
+// Provider sets the name of the provider of the service.
+func (s *ServiceBuilder) Provider(name string) *ServiceBuilder {
+	s.metadata().ProviderDisplayName = name
+	return s
+}
+
+// ImageURL sets the URL of the image shown for the service.
+func (s *ServiceBuilder) ImageURL(url string) *ServiceBuilder {
+	s.metadata().ImageURL = url
+	return s
+}
+
+// DocumentationURL sets the URL of the documentation of the service.
+func (s *ServiceBuilder) DocumentationURL(url string) *ServiceBuilder {
+	s.metadata().DocumentationURL = url
+	return s
+}
+
+// SupportURL sets the URL where users can get support for the service.
+func (s *ServiceBuilder) SupportURL(url string) *ServiceBuilder {
+	s.metadata().SupportURL = url
+	return s
+}
+
+// DashboardClient sets the OAuth client of the dashboard of the service.
+func (s *ServiceBuilder) DashboardClient(id, secret, redirectURI string) *ServiceBuilder {
+	s.service.DashboardClient = &domain.DashboardClient{
+		ID:          id,
+		Secret:      secret,
+		RedirectURI: redirectURI,
+	}
+	return s
+}
+
+// Plan adds a plan to the service.
+func (s *ServiceBuilder) Plan(name, description string) *PlanBuilder {
+	plan := &PlanBuilder{
+		plan: domain.Plan{
+			Name:        name,
+			Description: description,
+		},
+	}
+	s.plans = append(s.plans, plan)
+
+	return plan
+}
+
+func (s *ServiceBuilder) metadata() *domain.ServiceMetadata {
+	if s.service.Metadata == nil {
+		s.service.Metadata = &domain.ServiceMetadata{}
+	}
+
+	return s.service.Metadata
+}
+
+// ID replaces the ID derived from the names of the service and plan.
+func (p *PlanBuilder) ID(id string) *PlanBuilder {
+	p.plan.ID = id
+	return p
+}
+
+// DisplayName sets the name of the plan shown in user interfaces.
+func (p *PlanBuilder) DisplayName(name string) *PlanBuilder {
+	p.metadata().DisplayName = name
+	return p
+}
+
+// Bullets adds features of the plan shown in user interfaces.
+func (p *PlanBuilder) Bullets(bullets ...string) *PlanBuilder {
+	p.metadata().Bullets = append(p.metadata().Bullets, bullets...)
+	return p
+}
+
+// Cost adds a cost to the plan, such as USD(9.99) per "MONTHLY" unit,
+// and marks it as not free unless Free is called.
+func (p *PlanBuilder) Cost(amount domain.Amount, unit string) *PlanBuilder {
+	p.metadata().Costs = append(p.metadata().Costs, domain.Cost{Amount: amount, Unit: unit})
+	return p
+}
+
+// Monthly adds a monthly cost to the plan; see Cost.
+func (p *PlanBuilder) Monthly(amount domain.Amount) *PlanBuilder {
+	return p.Cost(amount, "MONTHLY")
+}
+
+// Free sets whether the plan is free, overriding the default derived from
+// its costs.
+func (p *PlanBuilder) Free(free bool) *PlanBuilder {
+	if free {
+		p.free = domain.FreeTrue
+	} else {
+		p.free = domain.FreeFalse
+	}
+	return p
+}
+
+func (p *PlanBuilder) metadata() *domain.PlanMetadata {
+	if p.plan.Metadata == nil {
+		p.plan.Metadata = &domain.PlanMetadata{}
+	}
+
+	return p.plan.Metadata
+}
+
+func (p *PlanBuilder) build(namespace uuid, serviceName string) domain.Plan {
+	plan := p.plan
+	if plan.ID == "" {
+		plan.ID = uuidV5(namespace, serviceName+"/"+plan.Name).String()
+	}
+
+	plan.Free = p.free
+	if plan.Free == nil {
+		plan.Free = domain.FreeTrue
+		if plan.Metadata != nil && len(plan.Metadata.Costs) > 0 {
+			plan.Free = domain.FreeFalse
+		}
+	}
+
+	if plan.Metadata != nil {
+		metadata := *plan.Metadata
+		metadata.Bullets = copyStrings(metadata.Bullets)
+		metadata.Costs = nil
+		for _, cost := range plan.Metadata.Costs {
+			amount := domain.Amount{}
+			for currency, value := range cost.Amount {
+				amount[currency] = value
+			}
+			metadata.Costs = append(metadata.Costs, domain.Cost{Amount: amount, Unit: cost.Unit})
+		}
+		plan.Metadata = &metadata
+	}
+
+	return plan
+}
+
+// USD returns an amount in US dollars.
+func USD(amount float64) domain.Amount {
+	return domain.Amount{"usd": amount}
+}
+
+// EUR returns an amount in euros.
+func EUR(amount float64) domain.Amount {
+	return domain.Amount{"eur": amount}
+}
+
+// GBP returns an amount in pounds sterling.
+func GBP(amount float64) domain.Amount {
+	return domain.Amount{"gbp": amount}
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append([]string(nil), s...)
+}
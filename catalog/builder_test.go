@@ -0,0 +1,120 @@
+package catalog_test
+
+import (
+	"github.com/pivotal-cf-experimental/envoy/catalog"
+	"github.com/pivotal-cf-experimental/envoy/domain"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Builder", func() {
+	const namespace = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	var builder *catalog.Builder
+
+	BeforeEach(func() {
+		builder = catalog.NewBuilder(namespace)
+	})
+
+	It("builds a catalog with defaults", func() {
+		postgres := builder.Service("python.org", "A PostgreSQL database").
+			Tags("sql").
+			DisplayName("PostgreSQL")
+		postgres.Plan("small", "A small database").Bullets("1 GB of storage")
+		postgres.Plan("large", "A large database").Monthly(catalog.USD(99)).Cost(catalog.EUR(0.1), "PER_GB")
+
+		built, err := builder.Build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(Equal(domain.Catalog{
+			Services: []domain.Service{{
+				ID:          "886313e1-3b8a-5372-9b90-0c9aee199e5d",
+				Name:        "python.org",
+				Description: "A PostgreSQL database",
+				Bindable:    true,
+				Tags:        []string{"sql"},
+				Metadata:    &domain.ServiceMetadata{DisplayName: "PostgreSQL"},
+				Plans: []domain.Plan{
+					{
+						ID:          built.Services[0].Plans[0].ID,
+						Name:        "small",
+						Description: "A small database",
+						Free:        domain.FreeTrue,
+						Metadata:    &domain.PlanMetadata{Bullets: []string{"1 GB of storage"}},
+					},
+					{
+						ID:          built.Services[0].Plans[1].ID,
+						Name:        "large",
+						Description: "A large database",
+						Free:        domain.FreeFalse,
+						Metadata: &domain.PlanMetadata{Costs: []domain.Cost{
+							{Amount: domain.Amount{"usd": 99}, Unit: "MONTHLY"},
+							{Amount: domain.Amount{"eur": 0.1}, Unit: "PER_GB"},
+						}},
+					},
+				},
+			}},
+		}))
+	})
+
+	It("derives the same IDs every time, unless they are given", func() {
+		builder.Service("postgres", "A database").Plan("small", "A small database")
+		first, err := builder.Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		other := catalog.NewBuilder(namespace)
+		other.Service("postgres", "A database").Plan("small", "A small database")
+		second, err := other.Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(second.Services[0].Plans[0].ID).To(Equal(first.Services[0].Plans[0].ID))
+		Expect(first.Services[0].Plans[0].ID).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+
+		builder.Service("mysql", "Another database").ID("mysql-id").Plan("small", "A small database").ID("plan-id")
+		built, err := builder.Build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built.Services[1].ID).To(Equal("mysql-id"))
+		Expect(built.Services[1].Plans[0].ID).To(Equal("plan-id"))
+	})
+
+	It("lets plans with a cost be free, and services not be bindable", func() {
+		builder.Service("postgres", "A database").Bindable(false).
+			Plan("trial", "A trial").Monthly(catalog.GBP(10)).Free(true)
+
+		built, err := builder.Build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built.Services[0].Bindable).To(BeFalse())
+		Expect(built.Services[0].Plans[0].Free).To(Equal(domain.FreeTrue))
+	})
+
+	It("returns catalogs unaffected by later changes to the builder", func() {
+		service := builder.Service("postgres", "A database").Tags("sql")
+		plan := service.Plan("small", "A small database").Monthly(catalog.USD(1))
+
+		built, err := builder.Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		service.Tags("relational")
+		plan.Bullets("fast").Monthly(catalog.USD(2))
+
+		Expect(built.Services[0].Tags).To(Equal([]string{"sql"}))
+		Expect(built.Services[0].Plans[0].Metadata.Bullets).To(BeEmpty())
+		Expect(built.Services[0].Plans[0].Metadata.Costs).To(HaveLen(1))
+	})
+
+	It("validates the catalog", func() {
+		builder.Service("Postgres DB", "A database")
+
+		_, err := builder.Build()
+		Expect(err).To(BeAssignableToTypeOf(domain.InvalidCatalogError("")))
+		Expect(err).To(MatchError(`invalid catalog: services[0]: name "Postgres DB" must only contain letters, digits, periods and hyphens; services[0]: no plans are offered`))
+	})
+
+	It("refuses namespaces that are not UUIDs", func() {
+		builder = catalog.NewBuilder("not-a-uuid")
+		builder.Service("postgres", "A database").Plan("small", "A small database")
+
+		_, err := builder.Build()
+		Expect(err).To(MatchError(`catalog: "not-a-uuid" is not a UUID`))
+	})
+})
@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decoder decodes YAML nodes into the catalog types of the domain
+// package, following their JSON field names. It collects every error
+// with its position, and remembers the node of every service and plan
+// so that validation problems can be located.
+type decoder struct {
+	loader *loader
+	paths  map[string]*yaml.Node
+	errors Errors
+}
+
+func (d *decoder) fail(node *yaml.Node, format string, args ...interface{}) {
+	d.errors = append(d.errors, d.loader.errorAt(node, fmt.Sprintf(format, args...)))
+}
+
+func (d *decoder) decode(node *yaml.Node, path string, out interface{}) {
+	d.value(node, path, reflect.ValueOf(out).Elem())
+}
+
+func (d *decoder) value(node *yaml.Node, path string, out reflect.Value) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	d.paths[path] = node
+
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+
+	switch out.Kind() {
+	case reflect.Ptr:
+		value := reflect.New(out.Type().Elem())
+		d.value(node, path, value.Elem())
+		out.Set(value)
+
+	case reflect.Struct:
+		d.structure(node, path, out)
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			d.fail(node, "%s must be a list", describe(path))
+			return
+		}
+
+		slice := reflect.MakeSlice(out.Type(), len(node.Content), len(node.Content))
+		for i, child := range node.Content {
+			d.value(child, fmt.Sprintf("%s[%d]", path, i), slice.Index(i))
+		}
+		out.Set(slice)
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			d.fail(node, "%s must be a mapping", describe(path))
+			return
+		}
+
+		m := reflect.MakeMapWithSize(out.Type(), len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			value := reflect.New(out.Type().Elem()).Elem()
+			d.value(node.Content[i+1], join(path, key), value)
+			m.SetMapIndex(reflect.ValueOf(key).Convert(out.Type().Key()), value)
+		}
+		out.Set(m)
+
+	default:
+		d.scalar(node, path, out)
+	}
+}
+
+func (d *decoder) structure(node *yaml.Node, path string, out reflect.Value) {
+	if node.Kind != yaml.MappingNode {
+		d.fail(node, "%s must be a mapping", describe(path))
+		return
+	}
+
+	fields := map[string]int{}
+	for i := 0; i < out.NumField(); i++ {
+		name := strings.Split(out.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		field, ok := fields[key.Value]
+		if !ok {
+			d.fail(key, "unknown field %q in %s", key.Value, describe(path))
+			continue
+		}
+
+		d.value(node.Content[i+1], join(path, key.Value), out.Field(field))
+	}
+}
+
+func (d *decoder) scalar(node *yaml.Node, path string, out reflect.Value) {
+	kinds := map[reflect.Kind]string{
+		reflect.String:  "a string",
+		reflect.Bool:    "a boolean",
+		reflect.Float64: "a number",
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		d.fail(node, "%s must be %s", describe(path), kinds[out.Kind()])
+		return
+	}
+
+	if out.Kind() == reflect.String {
+		out.SetString(node.Value)
+		return
+	}
+
+	if err := node.Decode(out.Addr().Interface()); err != nil {
+		d.fail(node, "%s must be %s", describe(path), kinds[out.Kind()])
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func describe(path string) string {
+	if path == "" {
+		return "the catalog"
+	}
+
+	return path
+}
@@ -0,0 +1,82 @@
+package catalog
+
+import (
+	"reflect"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
+
+// Change describes a service or plan that differs between two catalogs.
+type Change struct {
+	// Kind is "service" or "plan".
+	Kind string
+
+	// Change is "added", "removed" or "changed".
+	Change string
+
+	// ID and Name identify the service or plan.
+	ID   string
+	Name string
+}
+
+// Diff returns the services and plans added, removed or changed from the
+// old catalog to the new one. Services and plans are matched by ID; a
+// service whose plans changed, but not its own fields, is not reported
+// as changed.
+func Diff(old, new domain.Catalog) []Change {
+	var changes []Change
+
+	oldServices, oldPlans := index(old)
+	newServices, newPlans := index(new)
+
+	for _, service := range new.Services {
+		previous, ok := oldServices[service.ID]
+		if !ok {
+			changes = append(changes, Change{"service", "added", service.ID, service.Name})
+			continue
+		}
+
+		previous.Plans, service.Plans = nil, nil
+		if !reflect.DeepEqual(previous, service) {
+			changes = append(changes, Change{"service", "changed", service.ID, service.Name})
+		}
+	}
+	for _, service := range old.Services {
+		if _, ok := newServices[service.ID]; !ok {
+			changes = append(changes, Change{"service", "removed", service.ID, service.Name})
+		}
+	}
+
+	for _, service := range new.Services {
+		for _, plan := range service.Plans {
+			previous, ok := oldPlans[plan.ID]
+			if !ok {
+				changes = append(changes, Change{"plan", "added", plan.ID, plan.Name})
+			} else if !reflect.DeepEqual(previous, plan) {
+				changes = append(changes, Change{"plan", "changed", plan.ID, plan.Name})
+			}
+		}
+	}
+	for _, service := range old.Services {
+		for _, plan := range service.Plans {
+			if _, ok := newPlans[plan.ID]; !ok {
+				changes = append(changes, Change{"plan", "removed", plan.ID, plan.Name})
+			}
+		}
+	}
+
+	return changes
+}
+
+func index(catalog domain.Catalog) (map[string]domain.Service, map[string]domain.Plan) {
+	services := map[string]domain.Service{}
+	plans := map[string]domain.Plan{}
+	for _, service := range catalog.Services {
+		services[service.ID] = service
+		for _, plan := range service.Plans {
+			plans[plan.ID] = plan
+		}
+	}
+
+	return services, plans
+}
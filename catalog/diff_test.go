@@ -0,0 +1,50 @@
+package catalog_test
+
+import (
+	"github.com/pivotal-cf-experimental/envoy/catalog"
+	"github.com/pivotal-cf-experimental/envoy/domain"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff", func() {
+	old := domain.Catalog{
+		Services: []domain.Service{
+			{ID: "kept", Name: "kept", Description: "Kept", Plans: []domain.Plan{
+				{ID: "unchanged", Name: "unchanged"},
+				{ID: "changed", Name: "changed", Description: "Before"},
+				{ID: "removed", Name: "removed"},
+			}},
+			{ID: "gone", Name: "gone", Plans: []domain.Plan{{ID: "gone-plan", Name: "gone-plan"}}},
+		},
+	}
+
+	It("reports added, removed and changed services and plans", func() {
+		new := domain.Catalog{
+			Services: []domain.Service{
+				{ID: "kept", Name: "kept", Description: "Kept, but changed", Plans: []domain.Plan{
+					{ID: "unchanged", Name: "unchanged"},
+					{ID: "changed", Name: "changed", Description: "After"},
+					{ID: "added", Name: "added"},
+				}},
+				{ID: "new", Name: "new", Plans: []domain.Plan{{ID: "new-plan", Name: "new-plan"}}},
+			},
+		}
+
+		Expect(catalog.Diff(old, new)).To(Equal([]catalog.Change{
+			{Kind: "service", Change: "changed", ID: "kept", Name: "kept"},
+			{Kind: "service", Change: "added", ID: "new", Name: "new"},
+			{Kind: "service", Change: "removed", ID: "gone", Name: "gone"},
+			{Kind: "plan", Change: "changed", ID: "changed", Name: "changed"},
+			{Kind: "plan", Change: "added", ID: "added", Name: "added"},
+			{Kind: "plan", Change: "added", ID: "new-plan", Name: "new-plan"},
+			{Kind: "plan", Change: "removed", ID: "removed", Name: "removed"},
+			{Kind: "plan", Change: "removed", ID: "gone-plan", Name: "gone-plan"},
+		}))
+	})
+
+	It("reports nothing for identical catalogs", func() {
+		Expect(catalog.Diff(old, old)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,38 @@
+package catalog_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pivotal-cf-experimental/envoy"
+	"github.com/pivotal-cf-experimental/envoy/catalog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ envoy.VersionedCataloger = &catalog.File{}
+
+var _ = Describe("File", func() {
+	It("serves the loaded catalog", func() {
+		dir, err := os.MkdirTemp("", "catalog")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "catalog.yml")
+		Expect(os.WriteFile(path, []byte(`
+services:
+- id: service-id
+  name: postgres
+  description: A database
+  plans: [{id: plan-id, name: small, description: A small database}]
+`), 0600)).To(Succeed())
+
+		file, err := catalog.LoadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Catalog().Services[0].Name).To(Equal("postgres"))
+
+		_, err = catalog.LoadFile(filepath.Join(dir, "missing.yml"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,275 @@
+// Package catalog loads service broker catalogs from YAML or JSON files,
+// so that they can live next to deployment manifests, and builds them in
+// Go code with a Builder.
+//
+// String values may refer to environment variables as ${NAME}, or as
+// ${NAME:-default} to fall back to a default when the variable is not
+// set; $$ stands for a literal $. A mapping holding a single $include key
+// is replaced by the contents of the named file, resolved relative to the
+// including file, so that plans can be shared between services:
+//
+//	services:
+//	- id: ${SERVICE_ID}
+//	  name: postgres
+//	  description: A PostgreSQL database
+//	  plans:
+//	    $include: plans.yml
+//
+// An included sequence inside a sequence is spliced into it.
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"gopkg.in/yaml.v3"
+)
+
+const includeKey = "$include"
+
+// Error is an error type used to indicate a problem at a position in a
+// catalog file.
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error returns a string representation of the error message.
+func (e Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Errors is an error type used to list every problem found in a catalog
+// file.
+type Errors []Error
+
+// Error returns a string representation of the error message.
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Load reads the catalog in the YAML or JSON file at the given path,
+// resolving environment variables and includes, and validates it. It
+// returns an Errors listing every problem found with its position, or an
+// error if a file cannot be read.
+func Load(path string) (domain.Catalog, error) {
+	catalog, _, err := load(path)
+	return catalog, err
+}
+
+// load loads the catalog like Load, and also returns the paths of every
+// file that was read.
+func load(path string) (domain.Catalog, []string, error) {
+	l := loader{
+		files:     map[*yaml.Node]string{},
+		including: map[string]bool{},
+	}
+
+	root, err := l.load(path)
+	if err != nil {
+		return domain.Catalog{}, l.read, err
+	}
+
+	var catalog domain.Catalog
+	d := decoder{loader: &l, paths: map[string]*yaml.Node{}}
+	d.decode(root, "", &catalog)
+	if len(d.errors) > 0 {
+		return domain.Catalog{}, l.read, d.errors
+	}
+
+	var errs Errors
+	for _, problem := range catalog.Problems() {
+		node, ok := d.paths[problem.Path]
+		if !ok {
+			node = root
+		}
+		errs = append(errs, l.errorAt(node, "invalid catalog: "+problem.String()))
+	}
+	if len(errs) > 0 {
+		return domain.Catalog{}, l.read, errs
+	}
+
+	return catalog, l.read, nil
+}
+
+// loader reads catalog files, and remembers which file each node was read
+// from.
+type loader struct {
+	files     map[*yaml.Node]string
+	including map[string]bool
+	read      []string
+}
+
+func (l *loader) load(path string) (*yaml.Node, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.including[absolute] {
+		return nil, fmt.Errorf("catalog: %s includes itself", path)
+	}
+	l.including[absolute] = true
+	defer delete(l.including, absolute)
+
+	l.read = append(l.read, path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("catalog: %s: %s", path, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	if len(document.Content) == 0 {
+		return nil, Error{File: path, Line: 1, Column: 1, Message: "the file is empty"}
+	}
+
+	root := document.Content[0]
+	if err := l.resolve(root, path); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+// resolve interpolates environment variables in the scalars under node,
+// and replaces the includes it holds with the contents of the included
+// files.
+func (l *loader) resolve(node *yaml.Node, path string) error {
+	l.files[node] = path
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value, err := interpolate(node.Value)
+		if err != nil {
+			return l.errorAt(node, err.Error())
+		}
+		if value != node.Value {
+			node.Value = value
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+
+	case yaml.MappingNode:
+		if included, ok, err := l.include(node, path); ok || err != nil {
+			if err != nil {
+				return err
+			}
+			*node = *included
+			l.files[node] = l.files[included]
+			return nil
+		}
+
+		for _, child := range node.Content {
+			if err := l.resolve(child, path); err != nil {
+				return err
+			}
+		}
+
+	case yaml.SequenceNode:
+		var content []*yaml.Node
+		for _, child := range node.Content {
+			included, ok, err := l.include(child, path)
+			if err != nil {
+				return err
+			}
+
+			if ok && included.Kind == yaml.SequenceNode {
+				content = append(content, included.Content...)
+				continue
+			}
+			if ok {
+				*child = *included
+				l.files[child] = l.files[included]
+			} else if err := l.resolve(child, path); err != nil {
+				return err
+			}
+			content = append(content, child)
+		}
+		node.Content = content
+	}
+
+	return nil
+}
+
+// include loads the file named by node if it is a mapping holding a
+// single $include key.
+func (l *loader) include(node *yaml.Node, path string) (*yaml.Node, bool, error) {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 || node.Content[0].Value != includeKey {
+		return nil, false, nil
+	}
+
+	name := node.Content[1]
+	l.files[name] = path
+	if name.Kind != yaml.ScalarNode || name.Value == "" {
+		return nil, true, l.errorAt(name, includeKey+" must name a file")
+	}
+
+	included := filepath.Join(filepath.Dir(path), name.Value)
+	if filepath.IsAbs(name.Value) {
+		included = name.Value
+	}
+
+	root, err := l.load(included)
+	if err != nil {
+		if _, ok := err.(Error); ok {
+			return nil, true, err
+		}
+		return nil, true, l.errorAt(name, err.Error())
+	}
+
+	return root, true, nil
+}
+
+func (l *loader) errorAt(node *yaml.Node, message string) Error {
+	return Error{
+		File:    l.files[node],
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: message,
+	}
+}
+
+var variable = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces the references to environment variables in value.
+func interpolate(value string) (string, error) {
+	var missing []string
+	result := variable.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+
+		groups := variable.FindStringSubmatch(match)
+		if env, ok := os.LookupEnv(groups[1]); ok {
+			return env
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+
+		missing = append(missing, groups[1])
+		return match
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", missing[0])
+	}
+
+	return result, nil
+}
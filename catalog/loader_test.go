@@ -0,0 +1,190 @@
+package catalog_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pivotal-cf-experimental/envoy/catalog"
+	"github.com/pivotal-cf-experimental/envoy/domain"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Load", func() {
+	var dir string
+
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(contents), 0600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "catalog")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("loads a YAML catalog", func() {
+		path := write("catalog.yml", `
+services:
+- id: service-id
+  name: postgres
+  description: A PostgreSQL database
+  bindable: true
+  tags: [sql]
+  metadata:
+    displayName: PostgreSQL
+  plans:
+  - id: plan-id
+    name: small
+    description: A small database
+    free: false
+    metadata:
+      bullets: [1 GB of storage]
+      costs:
+      - amount: {usd: 9.99}
+        unit: MONTHLY
+`)
+
+		loaded, err := catalog.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(domain.Catalog{
+			Services: []domain.Service{{
+				ID:          "service-id",
+				Name:        "postgres",
+				Description: "A PostgreSQL database",
+				Bindable:    true,
+				Tags:        []string{"sql"},
+				Metadata:    &domain.ServiceMetadata{DisplayName: "PostgreSQL"},
+				Plans: []domain.Plan{{
+					ID:          "plan-id",
+					Name:        "small",
+					Description: "A small database",
+					Free:        domain.FreeFalse,
+					Metadata: &domain.PlanMetadata{
+						Bullets: []string{"1 GB of storage"},
+						Costs:   []domain.Cost{{Amount: domain.Amount{"usd": 9.99}, Unit: "MONTHLY"}},
+					},
+				}},
+			}},
+		}))
+	})
+
+	It("loads a JSON catalog", func() {
+		path := write("catalog.json", `{"services": [{"id": "service-id", "name": "postgres", "description": "A database",
+			"plans": [{"id": "plan-id", "name": "small", "description": "A small database"}]}]}`)
+
+		loaded, err := catalog.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Services[0].Plans[0].Name).To(Equal("small"))
+	})
+
+	It("interpolates environment variables", func() {
+		os.Setenv("CATALOG_TEST_SERVICE_ID", "from-the-environment")
+		os.Setenv("CATALOG_TEST_BINDABLE", "true")
+		defer os.Unsetenv("CATALOG_TEST_SERVICE_ID")
+		defer os.Unsetenv("CATALOG_TEST_BINDABLE")
+
+		path := write("catalog.yml", `
+services:
+- id: ${CATALOG_TEST_SERVICE_ID}
+  name: postgres
+  description: Costs $$5, ${CATALOG_TEST_UNSET:-or nothing}
+  bindable: ${CATALOG_TEST_BINDABLE}
+  plans: [{id: plan-id, name: small, description: A small database}]
+`)
+
+		loaded, err := catalog.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Services[0].ID).To(Equal("from-the-environment"))
+		Expect(loaded.Services[0].Description).To(Equal("Costs $5, or nothing"))
+		Expect(loaded.Services[0].Bindable).To(BeTrue())
+	})
+
+	It("reports environment variables that are not set", func() {
+		path := write("catalog.yml", "services:\n- id: ${CATALOG_TEST_UNSET}\n")
+
+		_, err := catalog.Load(path)
+		Expect(err).To(MatchError(path + ":2:7: environment variable CATALOG_TEST_UNSET is not set"))
+	})
+
+	It("includes shared definitions", func() {
+		write("plans.yml", `
+- id: small-plan
+  name: small
+  description: A small plan
+- id: large-plan
+  name: large
+  description: A large plan
+`)
+		write("extra.yml", "{id: extra-plan, name: extra, description: An extra plan}")
+		path := write("catalog.yml", `
+services:
+- id: service-id
+  name: postgres
+  description: A database
+  plans:
+  - $include: plans.yml
+  - $include: extra.yml
+`)
+
+		loaded, err := catalog.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, plan := range loaded.Services[0].Plans {
+			names = append(names, plan.Name)
+		}
+		Expect(names).To(Equal([]string{"small", "large", "extra"}))
+	})
+
+	It("reports missing and recursive includes", func() {
+		path := write("catalog.yml", "services:\n  $include: missing.yml\n")
+
+		_, err := catalog.Load(path)
+		Expect(err).To(MatchError(ContainSubstring(path + ":2:13: open " + filepath.Join(dir, "missing.yml"))))
+
+		path = write("catalog.yml", "services:\n  $include: catalog.yml\n")
+
+		_, err = catalog.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("includes itself")))
+	})
+
+	It("reports decoding errors with their positions", func() {
+		path := write("catalog.yml", `
+services:
+- id: service-id
+  name: postgres
+  bindable: maybe
+  colour: blue
+  plans: small
+`)
+
+		_, err := catalog.Load(path)
+		Expect(err).To(BeAssignableToTypeOf(catalog.Errors{}))
+		Expect(err).To(MatchError(path + `:5:13: services[0].bindable must be a boolean; ` +
+			path + `:6:3: unknown field "colour" in services[0]; ` +
+			path + `:7:10: services[0].plans must be a list`))
+	})
+
+	It("reports validation problems at the position of the service or plan", func() {
+		write("plans.yml", "- {id: plan-id, name: Small Plan, description: A small plan}\n")
+		path := write("catalog.yml", `
+services:
+- id: service-id
+  name: postgres
+  plans:
+    $include: plans.yml
+`)
+
+		_, err := catalog.Load(path)
+		Expect(err).To(MatchError(path + `:3:3: invalid catalog: services[0]: missing description; ` +
+			filepath.Join(dir, "plans.yml") + `:1:3: invalid catalog: services[0].plans[0]: name "Small Plan" must only contain letters, digits, periods and hyphens`))
+	})
+})
@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/metrics"
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader is a Cataloger serving the catalog loaded from a file, and
+// loading it again when the file, or a file it includes, changes, or
+// when the process receives SIGHUP. A reloaded catalog is validated
+// before it replaces the previous one; when it cannot be loaded, the
+// last good catalog keeps being served. It is safe for concurrent use.
+type Reloader struct {
+	path     string
+	logger   *slog.Logger
+	registry *metrics.Registry
+
+	mutex   sync.RWMutex
+	catalog domain.Catalog
+	version uint64
+	stamps  map[string]fileStamp
+}
+
+// NewReloader loads the catalog in the file at the given path; see Load.
+// Reloads are logged to the given logger, and recorded in the given
+// registry unless it is nil.
+func NewReloader(path string, logger *slog.Logger, registry *metrics.Registry) (*Reloader, error) {
+	r := &Reloader{
+		path:     path,
+		logger:   logger,
+		registry: registry,
+	}
+
+	catalog, files, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.catalog = catalog
+	r.stamps = stamp(files)
+
+	return r, nil
+}
+
+// Catalog returns the last catalog loaded successfully.
+func (r *Reloader) Catalog() domain.Catalog {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.catalog
+}
+
+// CatalogVersion returns the version of the served catalog, which
+// changes whenever a reload changes the catalog.
+func (r *Reloader) CatalogVersion() uint64 {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.version
+}
+
+// Reload loads the catalog again, and serves it if it is valid. It returns
+// the changes from the previous catalog, or the error that prevented the
+// reload. Both are logged and recorded.
+func (r *Reloader) Reload() ([]Change, error) {
+	catalog, files, err := load(r.path)
+
+	r.mutex.Lock()
+	r.stamps = stamp(files)
+	var changes []Change
+	if err == nil {
+		changes = Diff(r.catalog, catalog)
+		if !reflect.DeepEqual(r.catalog, catalog) {
+			r.version++
+		}
+		r.catalog = catalog
+	}
+	r.mutex.Unlock()
+
+	if r.registry != nil {
+		r.registry.CatalogReloaded(err)
+	}
+
+	if err != nil {
+		r.logger.Error("failed to reload the catalog, serving the last good one",
+			slog.String("path", r.path), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if r.registry != nil {
+			r.registry.CatalogChanged(change.Kind, change.Change)
+		}
+		r.logger.Info("catalog "+change.Kind+" "+change.Change,
+			slog.String("id", change.ID), slog.String("name", change.Name))
+	}
+	r.logger.Info("reloaded the catalog", slog.String("path", r.path), slog.Int("changes", len(changes)))
+
+	return changes, nil
+}
+
+// Watch reloads the catalog whenever the files it was loaded from have
+// changed, checking them every interval, or when the process receives
+// SIGHUP, until the context is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			r.Reload()
+		case <-ticker.C:
+			if r.changed() {
+				r.Reload()
+			}
+		}
+	}
+}
+
+func (r *Reloader) changed() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for file, previous := range r.stamps {
+		var current fileStamp
+		if info, err := os.Stat(file); err == nil {
+			current = fileStamp{info.ModTime(), info.Size()}
+		}
+
+		if current != previous {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stamp records the modification time and size of the files, or zero
+// stamps for those that cannot be read.
+func stamp(files []string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{info.ModTime(), info.Size()}
+		} else {
+			stamps[file] = fileStamp{}
+		}
+	}
+
+	return stamps
+}
@@ -0,0 +1,146 @@
+package catalog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy"
+	"github.com/pivotal-cf-experimental/envoy/catalog"
+	"github.com/pivotal-cf-experimental/envoy/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ envoy.VersionedCataloger = &catalog.Reloader{}
+
+const reloadedCatalog = `
+services:
+- id: service-id
+  name: postgres
+  description: A database
+  plans:
+    $include: plans.yml
+`
+
+var _ = Describe("Reloader", func() {
+	var dir string
+	var logs *bytes.Buffer
+	var registry *metrics.Registry
+	var reloader *catalog.Reloader
+
+	writePlans := func(contents string) {
+		path := filepath.Join(dir, "plans.yml")
+		Expect(os.WriteFile(path, []byte(contents), 0600)).To(Succeed())
+
+		// Make sure the change is noticed even within the resolution of
+		// the modification time.
+		later := time.Now().Add(time.Duration(len(contents)) * time.Second)
+		Expect(os.Chtimes(path, later, later)).To(Succeed())
+	}
+
+	scrape := func() string {
+		writer := httptest.NewRecorder()
+		registry.ServeHTTP(writer, httptest.NewRequest("GET", "/metrics", nil))
+		return writer.Body.String()
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "catalog")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(dir, "catalog.yml"), []byte(reloadedCatalog), 0600)).To(Succeed())
+		writePlans("- {id: small, name: small, description: A small plan}\n")
+
+		logs = &bytes.Buffer{}
+		registry = metrics.NewRegistry()
+		reloader, err = catalog.NewReloader(filepath.Join(dir, "catalog.yml"), slog.New(slog.NewTextHandler(logs, nil)), registry)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("serves the loaded catalog", func() {
+		Expect(reloader.Catalog().Services[0].Plans[0].ID).To(Equal("small"))
+	})
+
+	It("swaps in a valid catalog, reporting the changes", func() {
+		writePlans("- {id: large, name: large, description: A large plan}\n")
+
+		changes, err := reloader.Reload()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(ConsistOf(
+			catalog.Change{Kind: "plan", Change: "added", ID: "large", Name: "large"},
+			catalog.Change{Kind: "plan", Change: "removed", ID: "small", Name: "small"},
+		))
+		Expect(reloader.Catalog().Services[0].Plans[0].ID).To(Equal("large"))
+		Expect(reloader.CatalogVersion()).To(Equal(uint64(1)))
+
+		Expect(logs.String()).To(ContainSubstring(`msg="catalog plan added" id=large name=large`))
+		Expect(scrape()).To(ContainSubstring(`envoy_catalog_changes_total{kind="plan",change="added"} 1`))
+		Expect(scrape()).To(ContainSubstring(`envoy_catalog_reloads_total{result="success"} 1`))
+	})
+
+	It("keeps serving the last good catalog when the new one is invalid", func() {
+		writePlans("- {id: large, name: Large Plan, description: A large plan}\n")
+
+		_, err := reloader.Reload()
+		Expect(err).To(MatchError(ContainSubstring("must only contain letters, digits, periods and hyphens")))
+		Expect(reloader.Catalog().Services[0].Plans[0].ID).To(Equal("small"))
+		Expect(reloader.CatalogVersion()).To(BeZero())
+
+		Expect(logs.String()).To(ContainSubstring("failed to reload the catalog"))
+		Expect(scrape()).To(ContainSubstring(`envoy_catalog_reloads_total{result="failure"} 1`))
+	})
+
+	Context("when watching", func() {
+		var cancel context.CancelFunc
+		var done chan struct{}
+
+		BeforeEach(func() {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan struct{})
+			go func() {
+				reloader.Watch(ctx, 10*time.Millisecond)
+				close(done)
+			}()
+		})
+
+		AfterEach(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("reloads the catalog when an included file changes", func() {
+			writePlans("- {id: medium, name: medium, description: A medium plan}\n")
+
+			Eventually(func() string {
+				return reloader.Catalog().Services[0].Plans[0].ID
+			}).Should(Equal("medium"))
+		})
+
+		It("reloads the catalog on SIGHUP", func() {
+			// Keep the signal from terminating the tests until the
+			// reloader listens for it.
+			hangups := make(chan os.Signal, 1)
+			signal.Notify(hangups, syscall.SIGHUP)
+			defer signal.Stop(hangups)
+
+			Eventually(func() string {
+				Expect(syscall.Kill(os.Getpid(), syscall.SIGHUP)).To(Succeed())
+				return scrape()
+			}).Should(ContainSubstring(`envoy_catalog_reloads_total{result="success"}`))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package catalog
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// uuid is a UUID, as defined by RFC 4122.
+type uuid [16]byte
+
+func parseUUID(s string) (uuid, error) {
+	var u uuid
+
+	digits := strings.ReplaceAll(s, "-", "")
+	if len(s) != 36 || len(digits) != 32 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("catalog: %q is not a UUID", s)
+	}
+
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, fmt.Errorf("catalog: %q is not a UUID", s)
+	}
+
+	return u, nil
+}
+
+// uuidV5 returns the name-based UUID of the name in the namespace, using
+// SHA-1 as defined by RFC 4122.
+func uuidV5(namespace uuid, name string) uuid {
+	hash := sha1.New()
+	hash.Write(namespace[:])
+	hash.Write([]byte(name))
+
+	var u uuid
+	copy(u[:], hash.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+
+	return u
+}
+
+func (u uuid) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
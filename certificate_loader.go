@@ -0,0 +1,140 @@
+package envoy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certificateLoader loads the server certificate and key, and the CA
+// certificates used to verify client certificates, from files. The files
+// are checked before every TLS handshake, and loaded again when they have
+// changed, so that certificates can be renewed without a restart. When
+// the changed files cannot be loaded, the previous certificates keep
+// being used.
+type certificateLoader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	logger       *slog.Logger
+
+	mutex       sync.Mutex
+	stamps      map[string]fileStamp
+	certificate tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+func newCertificateLoader(certFile, keyFile, clientCAFile string, logger *slog.Logger) (*certificateLoader, error) {
+	loader := &certificateLoader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		logger:       logger,
+	}
+
+	if err := loader.load(); err != nil {
+		return nil, err
+	}
+
+	return loader, nil
+}
+
+func (l *certificateLoader) files() []string {
+	files := []string{l.certFile, l.keyFile}
+	if l.clientCAFile != "" {
+		files = append(files, l.clientCAFile)
+	}
+
+	return files
+}
+
+func (l *certificateLoader) load() error {
+	stamps := map[string]fileStamp{}
+	for _, file := range l.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		stamps[file] = fileStamp{info.ModTime(), info.Size()}
+	}
+
+	certificate, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if l.clientCAFile != "" {
+		pem, err := ioutil.ReadFile(l.clientCAFile)
+		if err != nil {
+			return err
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("envoy: no certificates found in %s", l.clientCAFile)
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.stamps = stamps
+	l.certificate = certificate
+	l.clientCAs = clientCAs
+
+	return nil
+}
+
+func (l *certificateLoader) changed() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, file := range l.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+
+		if l.stamps[file] != (fileStamp{info.ModTime(), info.Size()}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// config returns the TLS configuration for a new connection, reloading
+// the certificates first if their files have changed.
+func (l *certificateLoader) config(*tls.ClientHelloInfo) (*tls.Config, error) {
+	if l.changed() {
+		if err := l.load(); err != nil {
+			l.logger.Error("failed to reload TLS certificates", slog.String("error", err.Error()))
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{l.certificate},
+	}
+
+	if l.clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = l.clientCAs
+	}
+
+	return config, nil
+}
@@ -0,0 +1,96 @@
+package envoy
+
+import (
+	"crypto/x509"
+)
+
+// CertificateRule grants the client certificates it matches access to
+// broker operations. A rule matches a certificate when every identity
+// field set on the rule matches; a rule with no identity field set
+// matches nothing.
+type CertificateRule struct {
+	// CommonName matches the common name of the certificate subject.
+	CommonName string
+
+	// SAN matches any DNS name, email address, IP address or URI subject
+	// alternative name of the certificate.
+	SAN string
+
+	// Operations lists the operations the certificate may call:
+	// "catalog", "provision", "bind", "unbind", "deprovision",
+	// "last_operation", "metrics", "maintenance", "set_maintenance", or
+	// the operation of a route given with WithRoute. The operation "*"
+	// stands for all of them. The "healthz" and "readyz" probes served
+	// by WithHealthEndpoints are not subject to the policy.
+	Operations []string
+}
+
+// CertificatePolicy is a list of rules authorizing client certificates.
+// A certificate may call an operation when any of the rules matching it
+// grants access to that operation.
+type CertificatePolicy []CertificateRule
+
+// Allows reports whether the certificate may call the operation.
+func (p CertificatePolicy) Allows(certificate *x509.Certificate, operation string) bool {
+	for _, rule := range p {
+		if rule.matches(certificate) && rule.grants(operation) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r CertificateRule) matches(certificate *x509.Certificate) bool {
+	if r.CommonName == "" && r.SAN == "" {
+		return false
+	}
+
+	if r.CommonName != "" && certificate.Subject.CommonName != r.CommonName {
+		return false
+	}
+
+	if r.SAN != "" && !hasSAN(certificate, r.SAN) {
+		return false
+	}
+
+	return true
+}
+
+func (r CertificateRule) grants(operation string) bool {
+	for _, granted := range r.Operations {
+		if granted == "*" || granted == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasSAN(certificate *x509.Certificate, san string) bool {
+	for _, name := range certificate.DNSNames {
+		if name == san {
+			return true
+		}
+	}
+
+	for _, address := range certificate.EmailAddresses {
+		if address == san {
+			return true
+		}
+	}
+
+	for _, ip := range certificate.IPAddresses {
+		if ip.String() == san {
+			return true
+		}
+	}
+
+	for _, uri := range certificate.URIs {
+		if uri.String() == san {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package envoy_test
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+
+	"github.com/pivotal-cf-experimental/envoy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CertificatePolicy", func() {
+	var certificate *x509.Certificate
+
+	BeforeEach(func() {
+		spiffe, err := url.Parse("spiffe://example.com/cloud-controller")
+		Expect(err).NotTo(HaveOccurred())
+
+		certificate = &x509.Certificate{
+			Subject:        pkix.Name{CommonName: "cloud-controller"},
+			DNSNames:       []string{"cc.example.com"},
+			EmailAddresses: []string{"cc@example.com"},
+			IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+			URIs:           []*url.URL{spiffe},
+		}
+	})
+
+	It("matches certificates by common name", func() {
+		policy := envoy.CertificatePolicy{{CommonName: "cloud-controller", Operations: []string{"provision"}}}
+
+		Expect(policy.Allows(certificate, "provision")).To(BeTrue())
+		Expect(policy.Allows(certificate, "bind")).To(BeFalse())
+	})
+
+	It("matches certificates by any subject alternative name", func() {
+		for _, san := range []string{"cc.example.com", "cc@example.com", "10.0.0.1", "spiffe://example.com/cloud-controller"} {
+			policy := envoy.CertificatePolicy{{SAN: san, Operations: []string{"*"}}}
+
+			Expect(policy.Allows(certificate, "bind")).To(BeTrue(), san)
+		}
+	})
+
+	It("requires every identity field of a rule to match", func() {
+		policy := envoy.CertificatePolicy{{CommonName: "cloud-controller", SAN: "other.example.com", Operations: []string{"*"}}}
+
+		Expect(policy.Allows(certificate, "catalog")).To(BeFalse())
+	})
+
+	It("combines the operations granted by matching rules", func() {
+		policy := envoy.CertificatePolicy{
+			{CommonName: "cloud-controller", Operations: []string{"catalog"}},
+			{SAN: "cc.example.com", Operations: []string{"bind", "unbind"}},
+			{CommonName: "someone-else", Operations: []string{"provision"}},
+		}
+
+		Expect(policy.Allows(certificate, "catalog")).To(BeTrue())
+		Expect(policy.Allows(certificate, "unbind")).To(BeTrue())
+		Expect(policy.Allows(certificate, "provision")).To(BeFalse())
+	})
+
+	It("never matches rules without an identity", func() {
+		policy := envoy.CertificatePolicy{{Operations: []string{"*"}}}
+
+		Expect(policy.Allows(certificate, "catalog")).To(BeFalse())
+	})
+})
@@ -0,0 +1,89 @@
+// Command memory-broker serves an in-memory service broker, for exercising
+// a platform end to end during local development. Its instances and
+// bindings are forgotten when it exits.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy"
+	"github.com/pivotal-cf-experimental/envoy/catalog"
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/memory"
+)
+
+var defaultCatalog = domain.Catalog{
+	Services: []domain.Service{
+		{
+			ID:          "6c4c9fa8-3f0d-4a4e-9d43-0c2a5ae4dd1e",
+			Name:        "memory",
+			Description: "A service whose instances and bindings only exist in the memory of the broker.",
+			Bindable:    true,
+			Plans: []domain.Plan{
+				{
+					ID:          "1a0f5d2e-1c6b-4a0c-8f37-2b3c2bda7c41",
+					Name:        "default",
+					Description: "Provisions nothing, instantly.",
+					Free:        domain.FreeTrue,
+				},
+			},
+		},
+	},
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	username := flag.String("username", "admin", "Basic Auth username of the broker")
+	password := flag.String("password", "admin", "Basic Auth password of the broker")
+	delay := flag.Duration("delay", 0, "how long every operation takes; provisioning and deprovisioning run in the background when the platform accepts it")
+	catalogFile := flag.String("catalog", "", "YAML or JSON file holding the catalog to serve instead of the default one")
+	certFile := flag.String("cert", "", "PEM file holding the TLS certificate, to serve HTTPS")
+	keyFile := flag.String("key", "", "PEM file holding the TLS private key")
+	flag.Parse()
+
+	served := defaultCatalog
+	if *catalogFile != "" {
+		loaded, err := catalog.Load(*catalogFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		served = loaded
+	}
+
+	broker, err := memory.New(memory.Config{
+		Catalog:  served,
+		Username: *username,
+		Password: *password,
+		Delays: map[string]time.Duration{
+			"provision":   *delay,
+			"bind":        *delay,
+			"unbind":      *delay,
+			"deprovision": *delay,
+		},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	options := []envoy.Option{
+		envoy.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))),
+		envoy.WithHealthEndpoints(),
+	}
+	if *certFile != "" {
+		options = append(options, envoy.WithTLSCertificate(*certFile, *keyFile))
+	}
+
+	server, err := envoy.NewServer(*addr, broker, options...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := server.Run(context.Background()); err != nil {
+		log.Fatalln(err)
+	}
+}
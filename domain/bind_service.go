@@ -1,5 +1,7 @@
 package domain
 
+import "context"
+
 // BindRequest encapsulates the request payload information
 // for a bind request.
 type BindRequest struct {
@@ -24,6 +26,32 @@ type BindRequest struct {
 	// AppGUID is the GUID value of the application that the
 	// service instance is to be bound to in this bind request.
 	AppGUID string
+
+	// RequestID is the ID of the HTTP request, as given by the
+	// platform in the X-Broker-API-Request-Identity or X-Request-Id
+	// header, or generated by envoy. It can be used to correlate
+	// the broker's logs with those of the platform.
+	RequestID string
+
+	ctx context.Context
+}
+
+// Context returns the context of the bind request. It carries the
+// deadline and cancelation of the HTTP request, and the trace span of the
+// broker call when tracing is enabled. It is never nil.
+func (r BindRequest) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+// WithContext returns a copy of the bind request with its context
+// changed to ctx.
+func (r BindRequest) WithContext(ctx context.Context) BindRequest {
+	r.ctx = ctx
+	return r
 }
 
 // BindResponse encapsulates the response payload information
@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// InvalidCatalogError is an error type used to indicate that a catalog
+// would be rejected by Cloud Foundry. It lists every problem found.
+type InvalidCatalogError string
+
+// Error returns a string representation of the error message.
+func (e InvalidCatalogError) Error() string {
+	return "invalid catalog: " + string(e)
+}
+
+var cliFriendlyName = regexp.MustCompile(`^[A-Za-z0-9.-]+$`)
+
+// CatalogProblem is a problem found in a catalog by Problems. Path
+// locates the service or plan at fault, such as "services[0].plans[1]",
+// and is empty for problems with the catalog as a whole.
+type CatalogProblem struct {
+	Path    string
+	Message string
+}
+
+// String returns a string representation of the problem.
+func (p CatalogProblem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+
+	return p.Path + ": " + p.Message
+}
+
+// Validate checks that the catalog offers at least one service, that
+// every service and plan has an ID, a name and a description, that
+// service and plan names are command-line friendly, and that IDs and
+// names are unique where Cloud Foundry requires them to be. It returns an
+// InvalidCatalogError listing every problem found, or nil.
+func (c Catalog) Validate() error {
+	problems := c.Problems()
+	if len(problems) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(problems))
+	for i, problem := range problems {
+		descriptions[i] = problem.String()
+	}
+
+	return InvalidCatalogError(strings.Join(descriptions, "; "))
+}
+
+// Problems returns every problem reported by Validate, in order.
+func (c Catalog) Problems() []CatalogProblem {
+	var problems []CatalogProblem
+	problem := func(at, format string, args ...interface{}) {
+		problems = append(problems, CatalogProblem{Path: at, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.Services) == 0 {
+		problem("", "no services are offered")
+	}
+
+	serviceIDs := map[string]bool{}
+	serviceNames := map[string]bool{}
+	planIDs := map[string]bool{}
+	for i, service := range c.Services {
+		at := fmt.Sprintf("services[%d]", i)
+
+		checkOffering(at, service.ID, service.Name, service.Description, problem)
+		if service.ID != "" {
+			if serviceIDs[service.ID] {
+				problem(at, "id %q is not unique", service.ID)
+			}
+			serviceIDs[service.ID] = true
+		}
+		if service.Name != "" {
+			if serviceNames[service.Name] {
+				problem(at, "name %q is not unique", service.Name)
+			}
+			serviceNames[service.Name] = true
+		}
+
+		if len(service.Plans) == 0 {
+			problem(at, "no plans are offered")
+		}
+
+		planNames := map[string]bool{}
+		for j, plan := range service.Plans {
+			at := fmt.Sprintf("%s.plans[%d]", at, j)
+
+			checkOffering(at, plan.ID, plan.Name, plan.Description, problem)
+			if plan.ID != "" {
+				if planIDs[plan.ID] {
+					problem(at, "id %q is not unique", plan.ID)
+				}
+				planIDs[plan.ID] = true
+			}
+			if plan.Name != "" {
+				if planNames[plan.Name] {
+					problem(at, "name %q is not unique within the service", plan.Name)
+				}
+				planNames[plan.Name] = true
+			}
+		}
+	}
+
+	return problems
+}
+
+func checkOffering(at, id, name, description string, problem func(string, string, ...interface{})) {
+	if id == "" {
+		problem(at, "missing id")
+	}
+
+	if name == "" {
+		problem(at, "missing name")
+	} else if !cliFriendlyName.MatchString(name) {
+		problem(at, "name %q must only contain letters, digits, periods and hyphens", name)
+	}
+
+	if description == "" {
+		problem(at, "missing description")
+	}
+}
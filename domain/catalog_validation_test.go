@@ -0,0 +1,94 @@
+package domain_test
+
+import (
+	"github.com/pivotal-cf-experimental/envoy/domain"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog.Validate", func() {
+	var catalog domain.Catalog
+
+	BeforeEach(func() {
+		catalog = domain.Catalog{
+			Services: []domain.Service{
+				{
+					ID:          "service-id",
+					Name:        "my-service",
+					Description: "A service",
+					Plans: []domain.Plan{
+						{ID: "plan-1", Name: "small", Description: "A small plan"},
+						{ID: "plan-2", Name: "large", Description: "A large plan"},
+					},
+				},
+			},
+		}
+	})
+
+	It("accepts a valid catalog", func() {
+		Expect(catalog.Validate()).To(Succeed())
+	})
+
+	It("rejects an empty catalog", func() {
+		Expect(domain.Catalog{}.Validate()).To(MatchError("invalid catalog: no services are offered"))
+	})
+
+	It("lists every missing field", func() {
+		catalog.Services[0].Description = ""
+		catalog.Services[0].Plans[1] = domain.Plan{Name: "large"}
+
+		Expect(catalog.Validate()).To(MatchError("invalid catalog: services[0]: missing description; " +
+			"services[0].plans[1]: missing id; services[0].plans[1]: missing description"))
+	})
+
+	It("rejects names that are not command-line friendly", func() {
+		catalog.Services[0].Name = "My Service"
+
+		Expect(catalog.Validate()).To(MatchError(`invalid catalog: services[0]: name "My Service" must only contain letters, digits, periods and hyphens`))
+	})
+
+	It("accepts names in any case, and rejects underscores", func() {
+		catalog.Services[0].Name = "MySQL"
+		Expect(catalog.Validate()).To(Succeed())
+
+		catalog.Services[0].Plans[0].Name = "small_plan"
+		Expect(catalog.Validate()).To(MatchError(`invalid catalog: services[0].plans[0]: name "small_plan" must only contain letters, digits, periods and hyphens`))
+	})
+
+	It("rejects services without plans", func() {
+		catalog.Services[0].Plans = nil
+
+		Expect(catalog.Validate()).To(MatchError("invalid catalog: services[0]: no plans are offered"))
+	})
+
+	It("rejects duplicate IDs and names", func() {
+		catalog.Services = append(catalog.Services, domain.Service{
+			ID:          "service-id",
+			Name:        "my-service",
+			Description: "Another service",
+			Plans: []domain.Plan{
+				{ID: "plan-1", Name: "small", Description: "A small plan"},
+				{ID: "plan-3", Name: "small", Description: "Another small plan"},
+			},
+		})
+
+		err := catalog.Validate()
+		Expect(err).To(BeAssignableToTypeOf(domain.InvalidCatalogError("")))
+		Expect(err).To(MatchError(`invalid catalog: services[1]: id "service-id" is not unique; ` +
+			`services[1]: name "my-service" is not unique; ` +
+			`services[1].plans[0]: id "plan-1" is not unique; ` +
+			`services[1].plans[1]: name "small" is not unique within the service`))
+	})
+
+	It("locates every problem", func() {
+		catalog.Services[0].Plans[1].ID = ""
+
+		Expect(catalog.Problems()).To(Equal([]domain.CatalogProblem{
+			{Path: "services[0].plans[1]", Message: "missing id"},
+		}))
+		Expect(domain.Catalog{}.Problems()).To(Equal([]domain.CatalogProblem{
+			{Message: "no services are offered"},
+		}))
+	})
+})
@@ -0,0 +1,17 @@
+package domain
+
+// Credentials is a Basic Auth username and password pair accepted by the
+// service broker.
+type Credentials struct {
+	Username string
+
+	// Password is the password in plain text. It is ignored when
+	// PasswordHash is set.
+	Password string
+
+	// PasswordHash is a bcrypt hash of the password, or an argon2id
+	// hash in the PHC string format, such as
+	// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>, with the salt and
+	// key encoded in unpadded Base64.
+	PasswordHash string
+}
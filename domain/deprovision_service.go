@@ -1,5 +1,7 @@
 package domain
 
+import "context"
+
 // DeprovisionRequest encapsulates the request payload for a
 // deprovision request.
 type DeprovisionRequest struct {
@@ -16,4 +18,49 @@ type DeprovisionRequest struct {
 	// service catalog. This plan was specified when the
 	// service instance was provisioned.
 	PlanID string
+
+	// AcceptsIncomplete reports whether the platform accepts the
+	// service instance to be deprovisioned asynchronously, as given by
+	// the accepts_incomplete query parameter.
+	AcceptsIncomplete bool
+
+	// RequestID is the ID of the HTTP request, as given by the
+	// platform in the X-Broker-API-Request-Identity or X-Request-Id
+	// header, or generated by envoy. It can be used to correlate
+	// the broker's logs with those of the platform.
+	RequestID string
+
+	ctx context.Context
+}
+
+// Context returns the context of the deprovision request. It carries the
+// deadline and cancelation of the HTTP request, and the trace span of the
+// broker call when tracing is enabled. It is never nil.
+func (r DeprovisionRequest) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+// WithContext returns a copy of the deprovision request with its context
+// changed to ctx.
+func (r DeprovisionRequest) WithContext(ctx context.Context) DeprovisionRequest {
+	r.ctx = ctx
+	return r
+}
+
+// DeprovisionResponse encapsulates the response payload information
+// for a deprovision request.
+type DeprovisionResponse struct {
+	// Async reports that the service instance is being deprovisioned
+	// in the background. The platform then polls for the outcome of
+	// the operation with a LastOperationRequest. It may only be set
+	// when the request AcceptsIncomplete.
+	Async bool
+
+	// Operation optionally identifies the asynchronous operation. It
+	// is passed back in the LastOperationRequest.
+	Operation string
 }
@@ -40,3 +40,13 @@ type ServiceBindingNotFoundError string
 func (s ServiceBindingNotFoundError) Error() string {
 	return "The service binding was not found."
 }
+
+// ConcurrencyError is an error type used to indicate that another
+// operation is in progress on the service instance. The platform is
+// answered with a ConcurrencyRequest error, asking it to retry later.
+type ConcurrencyError string
+
+// Error returns a string representation of the error message.
+func (e ConcurrencyError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import "context"
+
+// LastOperationRequest encapsulates the request payload information for
+// a request polling the outcome of an asynchronous operation on a service
+// instance.
+type LastOperationRequest struct {
+	// InstanceID is the ID value for the service instance the
+	// operation is running on.
+	InstanceID string
+
+	// ServiceID is the ID value of the service provided in the
+	// service catalog. It may be empty.
+	ServiceID string
+
+	// PlanID is the ID value of the plan provided in the service
+	// catalog. It may be empty.
+	PlanID string
+
+	// Operation is the Operation returned when the asynchronous
+	// operation was started. It may be empty.
+	Operation string
+
+	// RequestID is the ID of the HTTP request, as given by the
+	// platform in the X-Broker-API-Request-Identity or X-Request-Id
+	// header, or generated by envoy. It can be used to correlate
+	// the broker's logs with those of the platform.
+	RequestID string
+
+	ctx context.Context
+}
+
+// Context returns the context of the last operation request. It carries
+// the deadline and cancelation of the HTTP request, and the trace span of
+// the broker call when tracing is enabled. It is never nil.
+func (r LastOperationRequest) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+// WithContext returns a copy of the last operation request with its
+// context changed to ctx.
+func (r LastOperationRequest) WithContext(ctx context.Context) LastOperationRequest {
+	r.ctx = ctx
+	return r
+}
+
+// LastOperationState is the state of an asynchronous operation.
+type LastOperationState string
+
+const (
+	InProgress LastOperationState = "in progress"
+	Succeeded  LastOperationState = "succeeded"
+	Failed     LastOperationState = "failed"
+)
+
+// LastOperationResponse encapsulates the response payload information
+// for a last operation request.
+type LastOperationResponse struct {
+	// State is the state of the operation.
+	State LastOperationState
+
+	// Description optionally describes the state of the operation to
+	// the user, such as the cause of a failure.
+	Description string
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "context"
+
 // ProvisionRequest encapsulates the request payload information
 // for a provision request.
 type ProvisionRequest struct {
@@ -22,6 +24,37 @@ type ProvisionRequest struct {
 	// SpaceGUID is GUID value of the space into which this service
 	// instance will be provisioned.
 	SpaceGUID string
+
+	// AcceptsIncomplete reports whether the platform accepts the
+	// service instance to be provisioned asynchronously, as given by
+	// the accepts_incomplete query parameter.
+	AcceptsIncomplete bool
+
+	// RequestID is the ID of the HTTP request, as given by the
+	// platform in the X-Broker-API-Request-Identity or X-Request-Id
+	// header, or generated by envoy. It can be used to correlate
+	// the broker's logs with those of the platform.
+	RequestID string
+
+	ctx context.Context
+}
+
+// Context returns the context of the provision request. It carries the
+// deadline and cancelation of the HTTP request, and the trace span of the
+// broker call when tracing is enabled. It is never nil.
+func (r ProvisionRequest) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+// WithContext returns a copy of the provision request with its context
+// changed to ctx.
+func (r ProvisionRequest) WithContext(ctx context.Context) ProvisionRequest {
+	r.ctx = ctx
+	return r
 }
 
 // ProvisionResponse encapsulates the response payload information
@@ -30,4 +63,14 @@ type ProvisionResponse struct {
 	// DashboardURL is the URL of a web-based management user
 	// interface for the service instance.
 	DashboardURL string
+
+	// Async reports that the service instance is being provisioned in
+	// the background. The platform then polls for the outcome of the
+	// operation with a LastOperationRequest. It may only be set when
+	// the request AcceptsIncomplete.
+	Async bool
+
+	// Operation optionally identifies the asynchronous operation. It
+	// is passed back in the LastOperationRequest.
+	Operation string
 }
@@ -1,5 +1,7 @@
 package domain
 
+import "context"
+
 // UnbindRequest encapsulates the request payload information
 // for an unbind request.
 type UnbindRequest struct {
@@ -20,4 +22,30 @@ type UnbindRequest struct {
 	// service catalog. This plan was specified when the
 	// service instance was provisioned.
 	PlanID string
+
+	// RequestID is the ID of the HTTP request, as given by the
+	// platform in the X-Broker-API-Request-Identity or X-Request-Id
+	// header, or generated by envoy. It can be used to correlate
+	// the broker's logs with those of the platform.
+	RequestID string
+
+	ctx context.Context
+}
+
+// Context returns the context of the unbind request. It carries the
+// deadline and cancelation of the HTTP request, and the trace span of the
+// broker call when tracing is enabled. It is never nil.
+func (r UnbindRequest) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+// WithContext returns a copy of the unbind request with its context
+// changed to ctx.
+func (r UnbindRequest) WithContext(ctx context.Context) UnbindRequest {
+	r.ctx = ctx
+	return r
 }
@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
+
+var (
+	errEncoding = errors.New("encryption: credentials could not be encoded")
+	errDecoding = errors.New("encryption: credentials could not be decoded")
+)
+
+// CredentialStore encrypts binding credentials before handing them to a
+// Store, and decrypts them on the way out. Each envelope is bound to its
+// binding ID, so an envelope copied to another ID fails to open.
+//
+// Decrypted credentials are never logged, and errors returned by the
+// CredentialStore never contain any part of them.
+type CredentialStore struct {
+	keyRing KeyRing
+	store   Store
+}
+
+// NewCredentialStore returns a CredentialStore that seals credentials
+// with the given key ring and persists them in the given store.
+func NewCredentialStore(keyRing KeyRing, store Store) CredentialStore {
+	return CredentialStore{
+		keyRing: keyRing,
+		store:   store,
+	}
+}
+
+// Put encrypts and stores the credentials for a service binding.
+func (s CredentialStore) Put(bindingID string, credentials domain.BindingCredentials) error {
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return errEncoding
+	}
+
+	envelope, err := s.keyRing.Seal(plaintext, []byte(bindingID))
+	if err != nil {
+		return err
+	}
+
+	return s.store.Put(bindingID, envelope)
+}
+
+// Get loads and decrypts the credentials for a service binding.
+func (s CredentialStore) Get(bindingID string) (domain.BindingCredentials, error) {
+	envelope, err := s.store.Get(bindingID)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.keyRing.Open(envelope, []byte(bindingID))
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials domain.BindingCredentials
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return nil, errDecoding
+	}
+
+	return credentials, nil
+}
+
+// Delete removes the credentials for a service binding.
+func (s CredentialStore) Delete(bindingID string) error {
+	return s.store.Delete(bindingID)
+}
+
+// Rotate rewraps every stored envelope that is not wrapped with the
+// primary key of the key ring. It returns the number of envelopes that
+// were rewrapped and the first error encountered; envelopes that fail to
+// rewrap are left untouched and do not stop the rotation. Envelopes put
+// or deleted while they are being rewrapped are left as they are, so
+// that a rotation never resurrects or overwrites credentials.
+func (s CredentialStore) Rotate() (int, error) {
+	ids, err := s.store.IDs()
+	if err != nil {
+		return 0, err
+	}
+
+	var rotated int
+	var firstErr error
+	for _, id := range ids {
+		err := s.rotate(id)
+		if err == errUpToDate || err == errChanged {
+			continue
+		}
+		if _, ok := err.(NotFoundError); ok {
+			continue
+		}
+
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		rotated++
+	}
+
+	return rotated, firstErr
+}
+
+// RotateInBackground calls Rotate every interval until the returned stop
+// function is called. Errors are passed to onError, which may be nil.
+func (s CredentialStore) RotateInBackground(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := s.Rotate(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+var (
+	errUpToDate = errors.New("encryption: envelope is wrapped with the primary key")
+	errChanged  = errors.New("encryption: envelope changed while it was being rewrapped")
+)
+
+func (s CredentialStore) rotate(id string) error {
+	envelope, err := s.store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if envelope.KeyID == s.keyRing.PrimaryID() {
+		return errUpToDate
+	}
+
+	rewrapped, err := s.keyRing.Rewrap(envelope)
+	if err != nil {
+		return err
+	}
+
+	swapped, err := s.store.CompareAndSwap(id, envelope, rewrapped)
+	if err != nil {
+		return err
+	}
+	if !swapped {
+		return errChanged
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package encryption_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/encryption"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// interleavingStore runs a function after every Get, to interleave other
+// operations with a rotation.
+type interleavingStore struct {
+	*encryption.MemoryStore
+	afterGet func(id string)
+}
+
+func (s interleavingStore) Get(id string) (encryption.Envelope, error) {
+	envelope, err := s.MemoryStore.Get(id)
+	if s.afterGet != nil {
+		s.afterGet(id)
+	}
+	return envelope, err
+}
+
+var _ = Describe("CredentialStore", func() {
+	var keys map[string][]byte
+	var store *encryption.MemoryStore
+	var credentialStore encryption.CredentialStore
+
+	newRing := func(primaryID string) encryption.KeyRing {
+		ring, err := encryption.NewKeyRing(primaryID, keys)
+		Expect(err).NotTo(HaveOccurred())
+		return ring
+	}
+
+	BeforeEach(func() {
+		keys = map[string][]byte{
+			"old": bytes.Repeat([]byte{1}, 32),
+			"new": bytes.Repeat([]byte{2}, 32),
+		}
+		store = encryption.NewMemoryStore()
+		credentialStore = encryption.NewCredentialStore(newRing("old"), store)
+	})
+
+	It("never hands plaintext credentials to the store", func() {
+		Expect(credentialStore.Put("binding-1", domain.BindingCredentials{
+			"password": "hunter2",
+		})).To(Succeed())
+
+		envelope, err := store.Get("binding-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		document, err := json.Marshal(envelope)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(document)).NotTo(ContainSubstring("hunter2"))
+	})
+
+	It("returns the decrypted credentials", func() {
+		Expect(credentialStore.Put("binding-1", domain.BindingCredentials{
+			"username": "admin",
+			"port":     3306,
+		})).To(Succeed())
+
+		credentials, err := credentialStore.Get("binding-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(domain.BindingCredentials{
+			"username": "admin",
+			"port":     float64(3306),
+		}))
+	})
+
+	It("refuses to open credentials moved to another binding", func() {
+		Expect(credentialStore.Put("binding-1", domain.BindingCredentials{"password": "hunter2"})).To(Succeed())
+
+		envelope, err := store.Get("binding-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Put("binding-2", envelope)).To(Succeed())
+
+		_, err = credentialStore.Get("binding-2")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("does not leak credential values in encoding errors", func() {
+		err := credentialStore.Put("binding-1", domain.BindingCredentials{"ratio": math.Inf(1)})
+		Expect(err).To(MatchError("encryption: credentials could not be encoded"))
+	})
+
+	It("deletes credentials", func() {
+		Expect(credentialStore.Put("binding-1", domain.BindingCredentials{})).To(Succeed())
+		Expect(credentialStore.Delete("binding-1")).To(Succeed())
+
+		_, err := credentialStore.Get("binding-1")
+		Expect(err).To(Equal(encryption.NotFoundError("binding-1")))
+	})
+
+	Describe("Rotate", func() {
+		BeforeEach(func() {
+			Expect(credentialStore.Put("binding-1", domain.BindingCredentials{"password": "one"})).To(Succeed())
+			Expect(credentialStore.Put("binding-2", domain.BindingCredentials{"password": "two"})).To(Succeed())
+			credentialStore = encryption.NewCredentialStore(newRing("new"), store)
+		})
+
+		It("rewraps envelopes that are not wrapped with the primary key", func() {
+			rotated, err := credentialStore.Rotate()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rotated).To(Equal(2))
+
+			envelope, err := store.Get("binding-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envelope.KeyID).To(Equal("new"))
+
+			credentials, err := credentialStore.Get("binding-2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials).To(Equal(domain.BindingCredentials{"password": "two"}))
+
+			rotated, err = credentialStore.Rotate()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rotated).To(Equal(0))
+		})
+
+		It("keeps rotating when an envelope cannot be rewrapped", func() {
+			Expect(store.Put("binding-0", encryption.Envelope{KeyID: "retired"})).To(Succeed())
+
+			rotated, err := credentialStore.Rotate()
+			Expect(err).To(Equal(encryption.UnknownKeyError("retired")))
+			Expect(rotated).To(Equal(2))
+		})
+
+		It("can rotate in the background", func() {
+			stop := credentialStore.RotateInBackground(time.Millisecond, nil)
+			defer stop()
+
+			Eventually(func() string {
+				envelope, err := store.Get("binding-2")
+				Expect(err).NotTo(HaveOccurred())
+				return envelope.KeyID
+			}).Should(Equal("new"))
+		})
+
+		It("does not resurrect credentials deleted during the rotation", func() {
+			interleaving := interleavingStore{MemoryStore: store}
+			interleaving.afterGet = func(id string) {
+				if id == "binding-1" {
+					store.Delete(id)
+				}
+			}
+
+			rotated, err := encryption.NewCredentialStore(newRing("new"), interleaving).Rotate()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rotated).To(Equal(1))
+
+			Expect(store.IDs()).To(Equal([]string{"binding-2"}))
+		})
+
+		It("does not overwrite credentials put during the rotation", func() {
+			interleaving := interleavingStore{MemoryStore: store}
+			interleaving.afterGet = func(id string) {
+				if id == "binding-1" {
+					interleaving.afterGet = nil
+					Expect(credentialStore.Put(id, domain.BindingCredentials{"password": "fresh"})).To(Succeed())
+				}
+			}
+
+			_, err := encryption.NewCredentialStore(newRing("new"), interleaving).Rotate()
+			Expect(err).NotTo(HaveOccurred())
+
+			credentials, err := credentialStore.Get("binding-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials).To(Equal(domain.BindingCredentials{"password": "fresh"}))
+		})
+
+		It("never resurrects credentials deleted concurrently with a rotation", func() {
+			ids := []string{"binding-1", "binding-2"}
+			for i := 0; i < 200; i++ {
+				id := fmt.Sprintf("binding-%03d", i)
+				Expect(encryption.NewCredentialStore(newRing("old"), store).Put(id, domain.BindingCredentials{"password": id})).To(Succeed())
+				ids = append(ids, id)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				for _, id := range ids {
+					credentialStore.Delete(id)
+				}
+			}()
+
+			credentialStore.Rotate()
+			<-done
+
+			Expect(store.IDs()).To(BeEmpty())
+		})
+	})
+})
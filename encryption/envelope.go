@@ -0,0 +1,159 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+const dataKeySize = 32
+
+var errAuthentication = errors.New("encryption: message authentication failed")
+
+// Envelope is a sealed message. The message is encrypted with a random
+// data key using AES-GCM, and the data key is itself encrypted (wrapped)
+// with a key from a KeyRing. Envelopes are safe to persist in clear text.
+type Envelope struct {
+	// KeyID is the ID of the key-encryption key that wrapped the
+	// data key.
+	KeyID string `json:"key_id"`
+
+	// WrappedKey is the nonce-prefixed, encrypted data key.
+	WrappedKey []byte `json:"wrapped_key"`
+
+	// Ciphertext is the nonce-prefixed, encrypted message.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Equal reports whether both envelopes hold the same sealed message,
+// wrapped with the same key.
+func (e Envelope) Equal(other Envelope) bool {
+	return e.KeyID == other.KeyID &&
+		bytes.Equal(e.WrappedKey, other.WrappedKey) &&
+		bytes.Equal(e.Ciphertext, other.Ciphertext)
+}
+
+// Seal encrypts plaintext into an Envelope wrapped with the primary key of
+// the key ring. The additionalData is authenticated but not encrypted, and
+// the same value must be given to Open.
+func (k KeyRing) Seal(plaintext, additionalData []byte) (Envelope, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return Envelope{}, err
+	}
+
+	ciphertext, err := seal(dataKey, plaintext, additionalData)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	envelope, err := k.wrap(dataKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	envelope.Ciphertext = ciphertext
+
+	return envelope, nil
+}
+
+// Open decrypts the message contained in the envelope.
+func (k KeyRing) Open(envelope Envelope, additionalData []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(aead, envelope.Ciphertext, additionalData)
+}
+
+// Rewrap returns a copy of the envelope with its data key wrapped by the
+// primary key of the key ring. The message itself is not decrypted.
+func (k KeyRing) Rewrap(envelope Envelope) (Envelope, error) {
+	dataKey, err := k.unwrap(envelope)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	rewrapped, err := k.wrap(dataKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	rewrapped.Ciphertext = envelope.Ciphertext
+
+	return rewrapped, nil
+}
+
+func (k KeyRing) wrap(dataKey []byte) (Envelope, error) {
+	aead, err := k.key(k.primaryID)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	wrappedKey, err := sealWith(aead, dataKey, []byte(k.primaryID))
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		KeyID:      k.primaryID,
+		WrappedKey: wrappedKey,
+	}, nil
+}
+
+func (k KeyRing) unwrap(envelope Envelope) ([]byte, error) {
+	aead, err := k.key(envelope.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(aead, envelope.WrappedKey, []byte(envelope.KeyID))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return sealWith(aead, plaintext, additionalData)
+}
+
+func sealWith(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errAuthentication
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, errAuthentication
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,83 @@
+package encryption_test
+
+import (
+	"bytes"
+
+	"github.com/pivotal-cf-experimental/envoy/encryption"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Envelope", func() {
+	var oldRing, newRing encryption.KeyRing
+
+	BeforeEach(func() {
+		var err error
+		keys := map[string][]byte{
+			"old": bytes.Repeat([]byte{1}, 32),
+			"new": bytes.Repeat([]byte{2}, 32),
+		}
+
+		oldRing, err = encryption.NewKeyRing("old", keys)
+		Expect(err).NotTo(HaveOccurred())
+
+		newRing, err = encryption.NewKeyRing("new", keys)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("seals and opens a message", func() {
+		envelope, err := oldRing.Seal([]byte("secret"), []byte("binding-1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envelope.KeyID).To(Equal("old"))
+		Expect(envelope.Ciphertext).NotTo(ContainSubstring("secret"))
+
+		plaintext, err := oldRing.Open(envelope, []byte("binding-1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(plaintext)).To(Equal("secret"))
+	})
+
+	It("refuses to open the message with different additional data", func() {
+		envelope, err := oldRing.Seal([]byte("secret"), []byte("binding-1"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = oldRing.Open(envelope, []byte("binding-2"))
+		Expect(err).To(MatchError("encryption: message authentication failed"))
+	})
+
+	It("refuses to open a tampered envelope", func() {
+		envelope, err := oldRing.Seal([]byte("secret"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		envelope.Ciphertext[len(envelope.Ciphertext)-1] ^= 0xff
+
+		_, err = oldRing.Open(envelope, nil)
+		Expect(err).To(MatchError("encryption: message authentication failed"))
+	})
+
+	It("refuses to open an envelope wrapped with an unknown key", func() {
+		envelope, err := oldRing.Seal([]byte("secret"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		envelope.KeyID = "missing"
+
+		_, err = oldRing.Open(envelope, nil)
+		Expect(err).To(Equal(encryption.UnknownKeyError("missing")))
+	})
+
+	Describe("Rewrap", func() {
+		It("wraps the data key with the primary key without changing the message", func() {
+			envelope, err := oldRing.Seal([]byte("secret"), []byte("binding-1"))
+			Expect(err).NotTo(HaveOccurred())
+
+			rewrapped, err := newRing.Rewrap(envelope)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rewrapped.KeyID).To(Equal("new"))
+			Expect(rewrapped.Ciphertext).To(Equal(envelope.Ciphertext))
+
+			plaintext, err := newRing.Open(rewrapped, []byte("binding-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(plaintext)).To(Equal("secret"))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package encryption_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEnvoyEncryptionSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Envoy Encryption Suite")
+}
@@ -0,0 +1,69 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// UnknownKeyError is an error type used to indicate that an
+// envelope was wrapped with a key that is not part of the key ring.
+type UnknownKeyError string
+
+// Error returns a string representation of the error message.
+func (e UnknownKeyError) Error() string {
+	return fmt.Sprintf("encryption: key %q is not in the key ring", string(e))
+}
+
+// KeyRing holds the key-encryption keys used to wrap the data keys of
+// sealed envelopes. Each key is identified by an ID that is recorded on
+// the envelopes it wraps, so envelopes sealed under an older key can still
+// be opened after the primary key has been rotated.
+type KeyRing struct {
+	primaryID string
+	keys      map[string]cipher.AEAD
+}
+
+// NewKeyRing returns a KeyRing containing the given AES keys, indexed by
+// key ID. New envelopes are always wrapped with the key named by primaryID.
+// Keys must be 16, 24 or 32 bytes long.
+func NewKeyRing(primaryID string, keys map[string][]byte) (KeyRing, error) {
+	if _, ok := keys[primaryID]; !ok {
+		return KeyRing{}, UnknownKeyError(primaryID)
+	}
+
+	ring := KeyRing{
+		primaryID: primaryID,
+		keys:      make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return KeyRing{}, fmt.Errorf("encryption: key %q: %s", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return KeyRing{}, fmt.Errorf("encryption: key %q: %s", id, err)
+		}
+
+		ring.keys[id] = aead
+	}
+
+	return ring, nil
+}
+
+// PrimaryID returns the ID of the key used to wrap new envelopes.
+func (k KeyRing) PrimaryID() string {
+	return k.primaryID
+}
+
+func (k KeyRing) key(id string) (cipher.AEAD, error) {
+	aead, ok := k.keys[id]
+	if !ok {
+		return nil, UnknownKeyError(id)
+	}
+
+	return aead, nil
+}
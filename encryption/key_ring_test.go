@@ -0,0 +1,43 @@
+package encryption_test
+
+import (
+	"bytes"
+
+	"github.com/pivotal-cf-experimental/envoy/encryption"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyRing", func() {
+	var keys map[string][]byte
+
+	BeforeEach(func() {
+		keys = map[string][]byte{
+			"key-1": bytes.Repeat([]byte{1}, 32),
+			"key-2": bytes.Repeat([]byte{2}, 16),
+		}
+	})
+
+	It("uses the given key as the primary key", func() {
+		ring, err := encryption.NewKeyRing("key-2", keys)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ring.PrimaryID()).To(Equal("key-2"))
+	})
+
+	Context("when the primary key is not in the ring", func() {
+		It("returns an UnknownKeyError", func() {
+			_, err := encryption.NewKeyRing("key-3", keys)
+			Expect(err).To(Equal(encryption.UnknownKeyError("key-3")))
+		})
+	})
+
+	Context("when a key has an invalid length", func() {
+		It("returns an error naming the key", func() {
+			keys["short"] = []byte("too-short")
+
+			_, err := encryption.NewKeyRing("key-1", keys)
+			Expect(err).To(MatchError(ContainSubstring(`"short"`)))
+		})
+	})
+})
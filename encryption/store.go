@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"sort"
+	"sync"
+)
+
+// NotFoundError is an error type used to indicate that no envelope
+// is stored under the requested ID.
+type NotFoundError string
+
+// Error returns a string representation of the error message.
+func (e NotFoundError) Error() string {
+	return "encryption: no envelope stored for " + string(e)
+}
+
+// Store defines the interface for a state store holding sealed
+// envelopes. A Store never receives plaintext credentials.
+type Store interface {
+	Put(id string, envelope Envelope) error
+	Get(id string) (Envelope, error)
+	Delete(id string) error
+	IDs() ([]string, error)
+
+	// CompareAndSwap replaces the envelope stored under the given ID
+	// with new, only if the stored envelope still equals old. It
+	// reports whether the envelope was replaced, and returns a
+	// NotFoundError if no envelope is stored under the ID.
+	CompareAndSwap(id string, old, new Envelope) (bool, error)
+}
+
+// MemoryStore is a Store that keeps envelopes in memory. It is safe for
+// concurrent use.
+type MemoryStore struct {
+	mutex     sync.RWMutex
+	envelopes map[string]Envelope
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		envelopes: map[string]Envelope{},
+	}
+}
+
+// Put stores the envelope under the given ID, replacing any existing
+// envelope.
+func (s *MemoryStore) Put(id string, envelope Envelope) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.envelopes[id] = envelope
+	return nil
+}
+
+// Get returns the envelope stored under the given ID, or a NotFoundError.
+func (s *MemoryStore) Get(id string) (Envelope, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	envelope, ok := s.envelopes[id]
+	if !ok {
+		return Envelope{}, NotFoundError(id)
+	}
+
+	return envelope, nil
+}
+
+// Delete removes the envelope stored under the given ID, or returns a
+// NotFoundError.
+func (s *MemoryStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.envelopes[id]; !ok {
+		return NotFoundError(id)
+	}
+
+	delete(s.envelopes, id)
+	return nil
+}
+
+// CompareAndSwap replaces the envelope stored under the given ID with
+// new if the stored envelope still equals old.
+func (s *MemoryStore) CompareAndSwap(id string, old, new Envelope) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.envelopes[id]
+	if !ok {
+		return false, NotFoundError(id)
+	}
+
+	if !stored.Equal(old) {
+		return false, nil
+	}
+
+	s.envelopes[id] = new
+	return true, nil
+}
+
+// IDs returns the sorted IDs of all stored envelopes.
+func (s *MemoryStore) IDs() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ids := make([]string, 0, len(s.envelopes))
+	for id := range s.envelopes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
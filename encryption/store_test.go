@@ -0,0 +1,60 @@
+package encryption_test
+
+import (
+	"github.com/pivotal-cf-experimental/envoy/encryption"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryStore", func() {
+	var store *encryption.MemoryStore
+
+	BeforeEach(func() {
+		store = encryption.NewMemoryStore()
+	})
+
+	It("stores and returns envelopes by ID", func() {
+		envelope := encryption.Envelope{KeyID: "key-1"}
+		Expect(store.Put("binding-1", envelope)).To(Succeed())
+
+		stored, err := store.Get("binding-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored).To(Equal(envelope))
+	})
+
+	It("lists the stored IDs in order", func() {
+		Expect(store.Put("b", encryption.Envelope{})).To(Succeed())
+		Expect(store.Put("a", encryption.Envelope{})).To(Succeed())
+
+		Expect(store.IDs()).To(Equal([]string{"a", "b"}))
+	})
+
+	It("deletes envelopes", func() {
+		Expect(store.Put("binding-1", encryption.Envelope{})).To(Succeed())
+		Expect(store.Delete("binding-1")).To(Succeed())
+
+		_, err := store.Get("binding-1")
+		Expect(err).To(Equal(encryption.NotFoundError("binding-1")))
+	})
+
+	It("returns a NotFoundError when deleting a missing envelope", func() {
+		Expect(store.Delete("missing")).To(Equal(encryption.NotFoundError("missing")))
+	})
+
+	It("swaps envelopes only while they are unchanged", func() {
+		old := encryption.Envelope{KeyID: "old", Ciphertext: []byte("one")}
+		new := encryption.Envelope{KeyID: "new", Ciphertext: []byte("one")}
+		Expect(store.Put("binding-1", old)).To(Succeed())
+
+		Expect(store.CompareAndSwap("binding-1", new, old)).To(BeFalse())
+		Expect(store.CompareAndSwap("binding-1", old, new)).To(BeTrue())
+
+		stored, err := store.Get("binding-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored).To(Equal(new))
+
+		_, err = store.CompareAndSwap("missing", old, new)
+		Expect(err).To(Equal(encryption.NotFoundError("missing")))
+	})
+})
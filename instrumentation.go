@@ -0,0 +1,140 @@
+package envoy
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/metrics"
+)
+
+// The instrumented operations wrap the broker, recording the time taken
+// by each call and the type of the errors it returns.
+
+type instrumentedProvisioner struct {
+	Provisioner
+	registry *metrics.Registry
+	async    *asyncOperations
+}
+
+func (p instrumentedProvisioner) Provision(request domain.ProvisionRequest) (domain.ProvisionResponse, error) {
+	start := time.Now()
+	response, err := p.Provisioner.Provision(request)
+	p.registry.OperationCompleted("provision", request.ServiceID, request.PlanID, time.Since(start), errorType(err))
+	if err == nil && response.Async {
+		p.async.started(request.InstanceID, "provision")
+	}
+	return response, err
+}
+
+type instrumentedBinder struct {
+	Binder
+	registry *metrics.Registry
+}
+
+func (b instrumentedBinder) Bind(request domain.BindRequest) (domain.BindResponse, error) {
+	start := time.Now()
+	response, err := b.Binder.Bind(request)
+	b.registry.OperationCompleted("bind", request.ServiceID, request.PlanID, time.Since(start), errorType(err))
+	return response, err
+}
+
+type instrumentedUnbinder struct {
+	Unbinder
+	registry *metrics.Registry
+}
+
+func (u instrumentedUnbinder) Unbind(request domain.UnbindRequest) error {
+	start := time.Now()
+	err := u.Unbinder.Unbind(request)
+	u.registry.OperationCompleted("unbind", request.ServiceID, request.PlanID, time.Since(start), errorType(err))
+	return err
+}
+
+type instrumentedDeprovisioner struct {
+	AsyncDeprovisioner
+	registry *metrics.Registry
+	async    *asyncOperations
+}
+
+func (d instrumentedDeprovisioner) DeprovisionAsync(request domain.DeprovisionRequest) (domain.DeprovisionResponse, error) {
+	start := time.Now()
+	response, err := d.AsyncDeprovisioner.DeprovisionAsync(request)
+	d.registry.OperationCompleted("deprovision", request.ServiceID, request.PlanID, time.Since(start), errorType(err))
+	if err == nil && response.Async {
+		d.async.started(request.InstanceID, "deprovision")
+	}
+	return response, err
+}
+
+type instrumentedLastOperationer struct {
+	LastOperationer
+	async *asyncOperations
+}
+
+func (l instrumentedLastOperationer) LastOperation(request domain.LastOperationRequest) (domain.LastOperationResponse, error) {
+	response, err := l.LastOperationer.LastOperation(request)
+	if errors.As(err, new(domain.ServiceInstanceNotFoundError)) || (err == nil && response.State != domain.InProgress) {
+		l.async.finished(request.InstanceID)
+	}
+	return response, err
+}
+
+// asyncOperations tracks the asynchronous operation in flight on each
+// service instance, from the 202 Accepted response starting it until the
+// platform is told it has finished, in the gauge of the registry.
+type asyncOperations struct {
+	registry *metrics.Registry
+
+	mutex    sync.Mutex
+	inFlight map[string]string
+}
+
+func newAsyncOperations(registry *metrics.Registry) *asyncOperations {
+	return &asyncOperations{
+		registry: registry,
+		inFlight: map[string]string{},
+	}
+}
+
+func (a *asyncOperations) started(instanceID, operation string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if previous, ok := a.inFlight[instanceID]; ok {
+		a.registry.AsyncOperationFinished(previous)
+	}
+	a.inFlight[instanceID] = operation
+	a.registry.AsyncOperationStarted(operation)
+}
+
+func (a *asyncOperations) finished(instanceID string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if operation, ok := a.inFlight[instanceID]; ok {
+		delete(a.inFlight, instanceID)
+		a.registry.AsyncOperationFinished(operation)
+	}
+}
+
+// errorType names the service broker API error an error is reported as.
+func errorType(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, new(domain.ServiceInstanceAlreadyExistsError)):
+		return "ServiceInstanceAlreadyExists"
+	case errors.As(err, new(domain.ServiceInstanceNotFoundError)):
+		return "ServiceInstanceNotFound"
+	case errors.As(err, new(domain.ServiceBindingAlreadyExistsError)):
+		return "ServiceBindingAlreadyExists"
+	case errors.As(err, new(domain.ServiceBindingNotFoundError)):
+		return "ServiceBindingNotFound"
+	case errors.As(err, new(domain.ConcurrencyError)):
+		return "ConcurrencyRequest"
+	default:
+		return "InternalError"
+	}
+}
@@ -1,13 +1,12 @@
 package handlers
 
 import (
-	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"net/http"
-	"regexp"
 
 	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
 )
 
 type binder interface {
@@ -16,30 +15,38 @@ type binder interface {
 
 type BindHandler struct {
 	binder
+	bodyPolicy BodyPolicy
+	idPolicy   IDPolicy
 }
 
-func NewBindHandler(binder binder) BindHandler {
+func NewBindHandler(binder binder, bodyPolicy BodyPolicy, idPolicy IDPolicy) BindHandler {
 	return BindHandler{
-		binder: binder,
+		binder:     binder,
+		bodyPolicy: bodyPolicy,
+		idPolicy:   idPolicy,
 	}
 }
 
 func (handler BindHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, span := tracing.Start(req.Context(), "BindHandler.Parse")
 	request, err := handler.Parse(req)
+	tracing.End(span, err)
 	if err != nil {
-		respond(w, http.StatusBadRequest, Failure{err.Error()})
+		respond(w, parseStatus(err), failure(req, err))
 		return
 	}
 
-	response, err := handler.binder.Bind(request)
+	ctx, span := tracing.Start(req.Context(), "Broker.Bind")
+	response, err := handler.binder.Bind(request.WithContext(ctx))
+	tracing.End(span, err)
 	if err != nil {
-		switch err.(type) {
-		case domain.ServiceBindingAlreadyExistsError:
+		switch {
+		case errors.As(err, new(domain.ServiceBindingAlreadyExistsError)):
 			respond(w, http.StatusConflict, EmptyJSON)
+		case errors.As(err, new(domain.ConcurrencyError)):
+			respond(w, http.StatusUnprocessableEntity, concurrencyFailure(req, err))
 		default:
-			respond(w, http.StatusInternalServerError, Failure{
-				Description: err.Error(),
-			})
+			respond(w, http.StatusInternalServerError, failure(req, err))
 		}
 		return
 	}
@@ -54,28 +61,38 @@ func (handler BindHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 }
 
 func (handler BindHandler) Parse(req *http.Request) (domain.BindRequest, error) {
-	body, err := ioutil.ReadAll(req.Body)
+	instanceID, err := handler.idPolicy.pathParam(req, "instance_id")
 	if err != nil {
-		panic(err)
+		return domain.BindRequest{}, err
+	}
+
+	bindingID, err := handler.idPolicy.pathParam(req, "binding_id")
+	if err != nil {
+		return domain.BindRequest{}, err
 	}
 
 	var params struct {
 		ServiceID string `json:"service_id"`
 		PlanID    string `json:"plan_id"`
 		AppGUID   string `json:"app_guid"`
+
+		// Fields of the Open Service Broker API that are not passed on
+		// to the broker, declared so that strict decoding accepts them.
+		Parameters   map[string]interface{} `json:"parameters"`
+		Context      map[string]interface{} `json:"context"`
+		BindResource map[string]interface{} `json:"bind_resource"`
 	}
-	err = json.Unmarshal(body, &params)
+	err = handler.bodyPolicy.decode(req, &params)
 	if err != nil {
-		return domain.BindRequest{}, errors.New("request body must be a JSON object")
+		return domain.BindRequest{}, err
 	}
 
-	expression := regexp.MustCompile(`^/v2/service_instances/(.*)/service_bindings/(.*)$`)
-	matches := expression.FindStringSubmatch(req.URL.Path)
-	instanceID, bindingID := matches[1], matches[2]
-
-	if len(instanceID) == 0 || len(bindingID) == 0 ||
-		len(params.ServiceID) == 0 || len(params.PlanID) == 0 {
-		return domain.BindRequest{}, errors.New("missing required field")
+	err = missingField(
+		"service_id", params.ServiceID,
+		"plan_id", params.PlanID,
+	)
+	if err != nil {
+		return domain.BindRequest{}, err
 	}
 
 	return domain.BindRequest{
@@ -84,5 +101,6 @@ func (handler BindHandler) Parse(req *http.Request) (domain.BindRequest, error)
 		ServiceID:  params.ServiceID,
 		PlanID:     params.PlanID,
 		AppGUID:    params.AppGUID,
+		RequestID:  requestid.FromContext(req.Context()),
 	}, nil
 }
@@ -43,7 +43,7 @@ var _ = Describe("BindHandler", func() {
 
 	BeforeEach(func() {
 		binder = NewBinder()
-		handler = handlers.NewBindHandler(binder)
+		handler = handlers.NewBindHandler(binder, handlers.BodyPolicy{}, handlers.IDPolicy{})
 	})
 
 	It("calls the binder Bind method with the correct values", func() {
@@ -61,6 +61,7 @@ var _ = Describe("BindHandler", func() {
 		if err != nil {
 			panic(err)
 		}
+		request = routed(request)
 
 		handler.ServeHTTP(writer, request)
 
@@ -70,7 +71,7 @@ var _ = Describe("BindHandler", func() {
 			ServiceID:  "service-id",
 			PlanID:     "plan-id",
 			AppGUID:    "app-guid",
-		}))
+		}.WithContext(request.Context())))
 	})
 
 	It("returns a 201 status code with an empty JSON body", func() {
@@ -88,6 +89,7 @@ var _ = Describe("BindHandler", func() {
 		if err != nil {
 			panic(err)
 		}
+		request = routed(request)
 
 		handler.ServeHTTP(writer, request)
 
@@ -123,6 +125,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -161,6 +164,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -192,6 +196,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -222,6 +227,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -240,6 +246,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -253,6 +260,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -275,6 +283,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -288,6 +297,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -317,6 +327,7 @@ var _ = Describe("BindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -330,7 +341,88 @@ var _ = Describe("BindHandler", func() {
 				ServiceID:  "service-id",
 				PlanID:     "plan-id",
 				AppGUID:    "",
-			}))
+			}.WithContext(request.Context())))
+		})
+	})
+
+	Context("when the request body has a field of the wrong type", func() {
+		It("should return a 400 naming the field", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","app_guid":["an-app"]}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(binder.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"field \"app_guid\" must be a string"}`))
+		})
+	})
+
+	Context("when the request body exceeds the maximum size", func() {
+		BeforeEach(func() {
+			handler = handlers.NewBindHandler(binder, handlers.BodyPolicy{MaxBytes: 16}, handlers.IDPolicy{})
+		})
+
+		It("should return a 413 and an error message", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","app_guid":"an-app"}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(binder.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"request body must not exceed 16 bytes"}`))
+		})
+	})
+
+	Context("when strict decoding is enabled", func() {
+		BeforeEach(func() {
+			handler = handlers.NewBindHandler(binder, handlers.BodyPolicy{Strict: true}, handlers.IDPolicy{})
+		})
+
+		It("should return a 400 naming an unknown field", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","app_guid":"an-app","app_id":"typo"}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(binder.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"unknown field \"app_id\""}`))
+		})
+
+		It("should accept a body with the fields of the service broker API", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","app_guid":"an-app","parameters":{},"context":{"platform":"cloudfoundry"},"bind_resource":{"app_guid":"an-app"}}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-guid/service_bindings/binding-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(binder.WasCalled).To(BeTrue())
+			Expect(writer.Code).To(Equal(http.StatusCreated))
 		})
 	})
 })
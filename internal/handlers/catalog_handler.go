@@ -1,7 +1,14 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pivotal-cf-experimental/envoy/domain"
 )
@@ -10,16 +17,111 @@ type cataloger interface {
 	Catalog() domain.Catalog
 }
 
+type versionedCataloger interface {
+	CatalogVersion() uint64
+}
+
+// CatalogHandler serves the catalog with a strong ETag and a
+// Last-Modified header, answering conditional requests with a 304 Not
+// Modified. The serialized catalog is cached for catalogers reporting
+// the version of their catalog, until the version changes; other
+// catalogers are asked for their catalog on every request.
 type CatalogHandler struct {
 	cataloger
+	cache *catalogCache
+}
+
+type catalogCache struct {
+	mutex     sync.Mutex
+	versioned bool
+	version   uint64
+	body      []byte
+	etag      string
+	modified  time.Time
 }
 
 func NewCatalogHandler(cataloger cataloger) CatalogHandler {
 	return CatalogHandler{
 		cataloger: cataloger,
+		cache:     &catalogCache{},
 	}
 }
 
 func (handler CatalogHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	respond(w, http.StatusOK, handler.cataloger.Catalog())
+	body, etag, modified := handler.serialize()
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	if notModified(req, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if req.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// notModified reports whether the request is conditional on a catalog
+// other than the one with the given ETag and modification time. Range
+// requests are not supported, and are answered with the whole catalog.
+func notModified(req *http.Request, etag string, modified time.Time) bool {
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// serialize returns the serialized catalog, its ETag, and when it was
+// first served.
+func (handler CatalogHandler) serialize() ([]byte, string, time.Time) {
+	cache := handler.cache
+	versioned, isVersioned := handler.cataloger.(versionedCataloger)
+
+	var version uint64
+	if isVersioned {
+		version = versioned.CatalogVersion()
+
+		cache.mutex.Lock()
+		if cache.versioned && cache.version == version {
+			defer cache.mutex.Unlock()
+			return cache.body, cache.etag, cache.modified
+		}
+		cache.mutex.Unlock()
+	}
+
+	body, err := json.Marshal(handler.cataloger.Catalog())
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if etag != cache.etag {
+		cache.body = body
+		cache.etag = etag
+		cache.modified = time.Now()
+	}
+	cache.versioned = isVersioned
+	cache.version = version
+
+	return cache.body, cache.etag, cache.modified
 }
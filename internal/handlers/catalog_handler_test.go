@@ -2,6 +2,7 @@ package handlers_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -19,6 +20,23 @@ func NewCataloger() Cataloger {
 	return Cataloger{}
 }
 
+type VersionedCataloger struct {
+	Cataloger
+	Version uint64
+	Calls   int
+}
+
+func (c *VersionedCataloger) Catalog() domain.Catalog {
+	c.Calls++
+	catalog := c.Cataloger.Catalog()
+	catalog.Services[0].Description = fmt.Sprintf("Version %d", c.Version)
+	return catalog
+}
+
+func (c *VersionedCataloger) CatalogVersion() uint64 {
+	return c.Version
+}
+
 func (c Cataloger) Catalog() domain.Catalog {
 	return domain.Catalog{
 		Services: []domain.Service{
@@ -106,4 +124,83 @@ var _ = Describe("CatalogHandler", func() {
 
 		Expect(responseStructure).To(Equal(cataloger.Catalog()))
 	})
+
+	serve := func(handler http.Handler, method string, headers map[string]string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest(method, "/v2/catalog", nil)
+		if err != nil {
+			panic(err)
+		}
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+
+		handler.ServeHTTP(writer, request)
+		return writer
+	}
+
+	It("answers conditional requests for an unchanged catalog with a 304", func() {
+		writer := serve(handler, "GET", nil)
+		etag := writer.Header().Get("ETag")
+		Expect(etag).To(MatchRegexp(`^"[0-9a-f]{32}"$`))
+		Expect(writer.Header().Get("Last-Modified")).NotTo(BeEmpty())
+
+		writer = serve(handler, "GET", map[string]string{"If-None-Match": etag})
+		Expect(writer.Code).To(Equal(http.StatusNotModified))
+		Expect(writer.Body.Len()).To(BeZero())
+
+		writer = serve(handler, "GET", map[string]string{"If-None-Match": `"something-else"`})
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Header().Get("ETag")).To(Equal(etag))
+	})
+
+	It("answers requests modified since the catalog was first served with a 304", func() {
+		modified := serve(handler, "GET", nil).Header().Get("Last-Modified")
+
+		writer := serve(handler, "GET", map[string]string{"If-Modified-Since": modified})
+		Expect(writer.Code).To(Equal(http.StatusNotModified))
+
+		writer = serve(handler, "GET", map[string]string{"If-Modified-Since": "Mon, 01 Jan 2001 00:00:00 GMT"})
+		Expect(writer.Code).To(Equal(http.StatusOK))
+	})
+
+	It("serves the whole catalog to range requests", func() {
+		whole := serve(handler, "GET", nil).Body.String()
+
+		writer := serve(handler, "GET", map[string]string{"Range": "bytes=0-9"})
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Header().Get("Accept-Ranges")).To(BeEmpty())
+		Expect(writer.Body.String()).To(Equal(whole))
+	})
+
+	It("serves HEAD requests without a body", func() {
+		writer := serve(handler, "HEAD", nil)
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Header().Get("ETag")).NotTo(BeEmpty())
+		Expect(writer.Body.Len()).To(BeZero())
+	})
+
+	Context("when the cataloger reports the version of its catalog", func() {
+		var versioned *VersionedCataloger
+
+		BeforeEach(func() {
+			versioned = &VersionedCataloger{}
+			handler = handlers.NewCatalogHandler(versioned)
+		})
+
+		It("caches the serialized catalog until the version changes", func() {
+			etag := serve(handler, "GET", nil).Header().Get("ETag")
+			serve(handler, "GET", nil)
+			Expect(versioned.Calls).To(Equal(1))
+
+			versioned.Version++
+
+			writer := serve(handler, "GET", map[string]string{"If-None-Match": etag})
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(writer.Header().Get("ETag")).NotTo(Equal(etag))
+			Expect(writer.Body.String()).To(ContainSubstring("Version 1"))
+			Expect(versioned.Calls).To(Equal(2))
+		})
+	})
 })
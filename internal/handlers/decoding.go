@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// BodyPolicy controls how request bodies are read and decoded.
+type BodyPolicy struct {
+	// MaxBytes is the largest request body accepted. Larger bodies
+	// are answered with a 413. Zero means no limit.
+	MaxBytes int64
+
+	// Strict rejects bodies containing unknown top-level fields.
+	Strict bool
+}
+
+// BodyTooLargeError is an error type used to indicate that a request
+// body exceeds the configured maximum size.
+type BodyTooLargeError int64
+
+// Error returns a string representation of the error message.
+func (e BodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body must not exceed %d bytes", int64(e))
+}
+
+// parseStatus returns the status code of the response to a request that
+// failed to parse.
+func parseStatus(err error) int {
+	if _, ok := err.(BodyTooLargeError); ok {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// decode reads the request body into params, a pointer to a struct, and
+// returns an error describing precisely which part of the body is
+// malformed.
+func (policy BodyPolicy) decode(req *http.Request, params interface{}) error {
+	var reader io.Reader = bytes.NewReader(nil)
+	if req.Body != nil {
+		reader = req.Body
+	}
+
+	if policy.MaxBytes > 0 {
+		reader = io.LimitReader(reader, policy.MaxBytes+1)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return errors.New("request body could not be read")
+	}
+
+	if policy.MaxBytes > 0 && int64(len(body)) > policy.MaxBytes {
+		return BodyTooLargeError(policy.MaxBytes)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if policy.Strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(params); err != nil {
+		return describeDecodingError(err)
+	}
+
+	if decoder.More() {
+		return errors.New("request body must be a single JSON object")
+	}
+
+	return nil
+}
+
+func describeDecodingError(err error) error {
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		return fmt.Errorf("request body must be a JSON object: %s at offset %d", err, err.Offset)
+	case *json.UnmarshalTypeError:
+		if err.Field == "" {
+			return errors.New("request body must be a JSON object")
+		}
+		return fmt.Errorf("field %q must be %s", err.Field, describeKind(err.Type.Kind()))
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("request body must be a JSON object")
+	}
+
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+
+	return fmt.Errorf("request body must be a JSON object: %s", err)
+}
+
+func describeKind(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "a number"
+	}
+}
+
+// missingField returns an error naming the first of the required fields,
+// given as name and value pairs, that is empty.
+func missingField(fields ...string) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if len(fields[i+1]) == 0 {
+			return fmt.Errorf("missing required field %q", fields[i])
+		}
+	}
+
+	return nil
+}
@@ -3,51 +3,69 @@ package handlers
 import (
 	"errors"
 	"net/http"
-	"regexp"
 
 	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
 )
 
 type deprovisioner interface {
-	Deprovision(domain.DeprovisionRequest) error
+	DeprovisionAsync(domain.DeprovisionRequest) (domain.DeprovisionResponse, error)
 }
 
 type DeprovisionHandler struct {
 	deprovisioner
+	idPolicy IDPolicy
 }
 
-func NewDeprovisionHandler(deprovisioner deprovisioner) DeprovisionHandler {
+func NewDeprovisionHandler(deprovisioner deprovisioner, idPolicy IDPolicy) DeprovisionHandler {
 	return DeprovisionHandler{
 		deprovisioner: deprovisioner,
+		idPolicy:      idPolicy,
 	}
 }
 
 func (handler DeprovisionHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, span := tracing.Start(req.Context(), "DeprovisionHandler.Parse")
 	request, err := handler.Parse(req)
+	tracing.End(span, err)
 	if err != nil {
-		respond(w, http.StatusBadRequest, Failure{err.Error()})
+		respond(w, http.StatusBadRequest, failure(req, err))
 		return
 	}
 
-	err = handler.deprovisioner.Deprovision(request)
+	ctx, span := tracing.Start(req.Context(), "Broker.Deprovision")
+	response, err := handler.deprovisioner.DeprovisionAsync(request.WithContext(ctx))
+	tracing.End(span, err)
 	if err != nil {
-		switch err.(type) {
-		case domain.ServiceInstanceNotFoundError:
+		switch {
+		case errors.As(err, new(domain.ServiceInstanceNotFoundError)):
 			respond(w, http.StatusGone, EmptyJSON)
+		case errors.As(err, new(domain.ConcurrencyError)):
+			respond(w, http.StatusUnprocessableEntity, concurrencyFailure(req, err))
 		default:
-			respond(w, http.StatusInternalServerError, Failure{
-				Description: err.Error(),
-			})
+			respond(w, http.StatusInternalServerError, failure(req, err))
 		}
 		return
 	}
 
+	if response.Async {
+		respond(w, http.StatusAccepted, struct {
+			Operation string `json:"operation,omitempty"`
+		}{
+			Operation: response.Operation,
+		})
+		return
+	}
+
 	respond(w, http.StatusOK, EmptyJSON)
 }
 
 func (handler DeprovisionHandler) Parse(req *http.Request) (domain.DeprovisionRequest, error) {
-	expression := regexp.MustCompile(`^/v2/service_instances/(.*)$`)
-	matches := expression.FindStringSubmatch(req.URL.Path)
+	instanceID, err := handler.idPolicy.pathParam(req, "instance_id")
+	if err != nil {
+		return domain.DeprovisionRequest{}, err
+	}
 
 	serviceIDValues := req.URL.Query()["service_id"]
 	planIDValues := req.URL.Query()["plan_id"]
@@ -57,8 +75,10 @@ func (handler DeprovisionHandler) Parse(req *http.Request) (domain.DeprovisionRe
 	}
 
 	return domain.DeprovisionRequest{
-		InstanceID: matches[1],
-		ServiceID:  serviceIDValues[0],
-		PlanID:     planIDValues[0],
+		InstanceID:        instanceID,
+		ServiceID:         serviceIDValues[0],
+		PlanID:            planIDValues[0],
+		AcceptsIncomplete: acceptsIncomplete(req),
+		RequestID:         requestid.FromContext(req.Context()),
 	}, nil
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/gorilla/mux"
 	"github.com/pivotal-cf-experimental/envoy/domain"
 	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
 
@@ -15,15 +16,16 @@ import (
 )
 
 type Deprovisioner struct {
-	WasCalledWith    domain.DeprovisionRequest
-	WasCalled        bool
-	DeprovisionError error
+	WasCalledWith       domain.DeprovisionRequest
+	WasCalled           bool
+	DeprovisionResponse domain.DeprovisionResponse
+	DeprovisionError    error
 }
 
-func (d *Deprovisioner) Deprovision(deprovisionRequest domain.DeprovisionRequest) error {
+func (d *Deprovisioner) DeprovisionAsync(deprovisionRequest domain.DeprovisionRequest) (domain.DeprovisionResponse, error) {
 	d.WasCalledWith = deprovisionRequest
 	d.WasCalled = true
-	return d.DeprovisionError
+	return d.DeprovisionResponse, d.DeprovisionError
 }
 
 func NewDeprovisioner() *Deprovisioner {
@@ -36,7 +38,7 @@ var _ = Describe("DeprovisionHandler", func() {
 
 	BeforeEach(func() {
 		deprovisioner = NewDeprovisioner()
-		handler = handlers.NewDeprovisionHandler(deprovisioner)
+		handler = handlers.NewDeprovisionHandler(deprovisioner, handlers.IDPolicy{})
 	})
 
 	It("calls the deprovisioner Deprovision method with the correct values", func() {
@@ -50,6 +52,7 @@ var _ = Describe("DeprovisionHandler", func() {
 		if err != nil {
 			panic(err)
 		}
+		request = routed(request)
 
 		handler.ServeHTTP(writer, request)
 
@@ -57,7 +60,7 @@ var _ = Describe("DeprovisionHandler", func() {
 			InstanceID: "service-instance-id",
 			ServiceID:  "the-sshfs-service-id",
 			PlanID:     "the-1gb-plan-id",
-		}))
+		}.WithContext(request.Context())))
 
 	})
 
@@ -70,6 +73,7 @@ var _ = Describe("DeprovisionHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -81,6 +85,27 @@ var _ = Describe("DeprovisionHandler", func() {
 		})
 	})
 
+	Context("when the deprovisioner finishes asynchronously", func() {
+		It("returns a 202 Accepted with the operation", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("DELETE",
+				"/v2/service_instances/service-instance-id?plan_id=some-plan-id&service_id=some-service-id&accepts_incomplete=true",
+				nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			deprovisioner.DeprovisionResponse = domain.DeprovisionResponse{Async: true, Operation: "an-operation"}
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(deprovisioner.WasCalledWith.AcceptsIncomplete).To(BeTrue())
+			Expect(writer.Code).To(Equal(http.StatusAccepted))
+			Expect(writer.Body.String()).To(MatchJSON(`{"operation":"an-operation"}`))
+		})
+	})
+
 	Context("when the service instance does not exist", func() {
 		It("returns a 410 Gone with JSON {}", func() {
 			writer := httptest.NewRecorder()
@@ -90,6 +115,7 @@ var _ = Describe("DeprovisionHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			deprovisioner.DeprovisionError = domain.ServiceInstanceNotFoundError("that instance doesn't exist!")
 
@@ -101,6 +127,47 @@ var _ = Describe("DeprovisionHandler", func() {
 		})
 	})
 
+	Context("when the deprovisioner wraps the error reporting that the service instance does not exist", func() {
+		It("returns a 410 Gone with JSON {}", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("DELETE",
+				"/v2/service_instances/a-missing-service-instance-id?plan_id=some-plan-id&service_id=some-service-id",
+				nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			deprovisioner.DeprovisionError = fmt.Errorf("deprovisioning: %w", domain.ServiceInstanceNotFoundError("that instance doesn't exist!"))
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusGone))
+			Expect(writer.Body.String()).To(MatchJSON("{}"))
+		})
+	})
+
+	Context("when another operation is in progress on the service instance", func() {
+		It("returns a 422 Unprocessable Entity with a ConcurrencyRequest error", func() {
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("DELETE",
+				"/v2/service_instances/a-busy-service-instance-id?plan_id=some-plan-id&service_id=some-service-id",
+				nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			deprovisioner.DeprovisionError = domain.ConcurrencyError("an operation is in progress")
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(writer.Header()["Content-Type"]).To(Equal([]string{"application/json"}))
+			Expect(writer.Body.String()).To(MatchJSON(`{"error":"ConcurrencyRequest","description":"an operation is in progress"}`))
+		})
+	})
+
 	Context("when the deprovisioner fails", func() {
 		It("returns a 500 error with the message", func() {
 			writer := httptest.NewRecorder()
@@ -109,6 +176,7 @@ var _ = Describe("DeprovisionHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			deprovisioner.DeprovisionError = errors.New("my database failed somehow!")
 
@@ -129,6 +197,7 @@ var _ = Describe("DeprovisionHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -143,6 +212,7 @@ var _ = Describe("DeprovisionHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -155,4 +225,99 @@ var _ = Describe("DeprovisionHandler", func() {
 			Expect(msg.Description).To(ContainSubstring("service_id"))
 		})
 	})
+
+	Context("when the instance ID is percent-encoded", func() {
+		It("calls the deprovisioner with the decoded ID", func() {
+			writer := httptest.NewRecorder()
+
+			url := "/v2/service_instances/an%20instance%2Fid?plan_id=some-plan-id&service_id=some-service-id"
+			request, err := http.NewRequest("DELETE", url, nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(deprovisioner.WasCalledWith.InstanceID).To(Equal("an instance/id"))
+		})
+	})
+
+	Context("when an ID policy is configured", func() {
+		BeforeEach(func() {
+			handler = handlers.NewDeprovisionHandler(deprovisioner, handlers.IDPolicy{
+				MaxLength: 36,
+				Pattern:   handlers.GUIDPattern,
+			})
+		})
+
+		It("accepts IDs matching the policy", func() {
+			writer := httptest.NewRecorder()
+
+			url := "/v2/service_instances/a3bb8f67-2f6f-4a5e-a6bb-2c3a9f0b2ecd?plan_id=some-plan-id&service_id=some-service-id"
+			request, err := http.NewRequest("DELETE", url, nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(deprovisioner.WasCalled).To(BeTrue())
+		})
+
+		It("rejects IDs that do not match the pattern", func() {
+			writer := httptest.NewRecorder()
+
+			url := "/v2/service_instances/not-a-guid?plan_id=some-plan-id&service_id=some-service-id"
+			request, err := http.NewRequest("DELETE", url, nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(deprovisioner.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"path parameter \"instance_id\" has an invalid value \"not-a-guid\""}`))
+		})
+
+		It("rejects IDs that are too long", func() {
+			writer := httptest.NewRecorder()
+
+			url := "/v2/service_instances/a3bb8f67-2f6f-4a5e-a6bb-2c3a9f0b2ecd-and-then-some?plan_id=some-plan-id&service_id=some-service-id"
+			request, err := http.NewRequest("DELETE", url, nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(deprovisioner.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"path parameter \"instance_id\" must not be longer than 36 characters"}`))
+		})
+	})
+
+	Context("when the instance ID is not correctly percent-encoded", func() {
+		It("should return a 400 error", func() {
+			writer := httptest.NewRecorder()
+
+			request, err := http.NewRequest("DELETE", "/v2/service_instances/instance-id?plan_id=some-plan-id&service_id=some-service-id", nil)
+			if err != nil {
+				panic(err)
+			}
+			request = mux.SetURLVars(request, map[string]string{"instance_id": "instance%zzid"})
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(deprovisioner.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"path parameter \"instance_id\" is not correctly percent-encoded"}`))
+		})
+	})
 })
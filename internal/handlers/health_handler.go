@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
+)
+
+// Check is a named readiness check.
+type Check struct {
+	Name  string
+	Check func(context.Context) error
+}
+
+// HealthHandler answers liveness probes. It always responds with a 200,
+// since serving the request is proof enough that the process is alive.
+type HealthHandler struct{}
+
+func NewHealthHandler() HealthHandler {
+	return HealthHandler{}
+}
+
+func (handler HealthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	respond(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadinessHandler answers readiness probes, responding with a 200 when
+// every check passes and a 503 otherwise. The response names the failing
+// checks without describing their errors, since the endpoint is not
+// authenticated; the errors are recorded on the span of each check.
+type ReadinessHandler struct {
+	checks []Check
+}
+
+func NewReadinessHandler(checks ...Check) ReadinessHandler {
+	return ReadinessHandler{
+		checks: checks,
+	}
+}
+
+func (handler ReadinessHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := http.StatusOK
+	results := map[string]string{}
+
+	for _, check := range handler.checks {
+		ctx, span := tracing.Start(req.Context(), "Readiness."+check.Name)
+		err := check.Check(ctx)
+		tracing.End(span, err)
+
+		if err != nil {
+			status = http.StatusServiceUnavailable
+			results[check.Name] = "failing"
+			continue
+		}
+
+		results[check.Name] = "ok"
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}{"ok", results}
+	if status != http.StatusOK {
+		response.Status = "unavailable"
+	}
+
+	respond(w, status, response)
+}
@@ -0,0 +1,68 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HealthHandler", func() {
+	It("returns a 200", func() {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest("GET", "/healthz", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		handlers.NewHealthHandler().ServeHTTP(writer, request)
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Body.String()).To(MatchJSON(`{"status":"ok"}`))
+	})
+})
+
+var _ = Describe("ReadinessHandler", func() {
+	var backendErr error
+	var handler handlers.ReadinessHandler
+
+	BeforeEach(func() {
+		backendErr = nil
+		handler = handlers.NewReadinessHandler(
+			handlers.Check{Name: "catalog", Check: func(context.Context) error { return nil }},
+			handlers.Check{Name: "broker", Check: func(context.Context) error { return backendErr }},
+		)
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest("GET", "/readyz", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		handler.ServeHTTP(writer, request)
+		return writer
+	}
+
+	It("returns a 200 when every check passes", func() {
+		writer := serve()
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Body.String()).To(MatchJSON(`{"status":"ok","checks":{"catalog":"ok","broker":"ok"}}`))
+	})
+
+	It("returns a 503 naming the failing checks, without their errors", func() {
+		backendErr = errors.New("dial tcp 10.0.0.1:5432: connection refused")
+
+		writer := serve()
+
+		Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(writer.Body.String()).To(MatchJSON(`{"status":"unavailable","checks":{"catalog":"ok","broker":"failing"}}`))
+	})
+})
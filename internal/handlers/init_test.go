@@ -1,8 +1,11 @@
 package handlers_test
 
 import (
+	"net/http"
 	"testing"
 
+	"github.com/gorilla/mux"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -11,3 +14,19 @@ func TestEnvoyHandlersSuite(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Envoy Handlers Suite")
 }
+
+// routed returns a copy of the request carrying the path variables that
+// the broker's router would extract from its encoded path.
+func routed(request *http.Request) *http.Request {
+	router := mux.NewRouter().UseEncodedPath()
+	router.Path("/v2/service_instances/{instance_id}")
+	router.Path("/v2/service_instances/{instance_id}/service_bindings/{binding_id}")
+	router.Path("/v2/service_instances/{instance_id}/last_operation")
+
+	var match mux.RouteMatch
+	if !router.Match(request, &match) {
+		return request
+	}
+
+	return mux.SetURLVars(request, match.Vars)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
+)
+
+type lastOperationer interface {
+	LastOperation(domain.LastOperationRequest) (domain.LastOperationResponse, error)
+}
+
+// LastOperationHandler answers the platform polling for the outcome of an
+// asynchronous operation on a service instance.
+type LastOperationHandler struct {
+	lastOperationer
+	idPolicy IDPolicy
+}
+
+func NewLastOperationHandler(lastOperationer lastOperationer, idPolicy IDPolicy) LastOperationHandler {
+	return LastOperationHandler{
+		lastOperationer: lastOperationer,
+		idPolicy:        idPolicy,
+	}
+}
+
+func (handler LastOperationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, span := tracing.Start(req.Context(), "LastOperationHandler.Parse")
+	request, err := handler.Parse(req)
+	tracing.End(span, err)
+	if err != nil {
+		respond(w, http.StatusBadRequest, failure(req, err))
+		return
+	}
+
+	ctx, span := tracing.Start(req.Context(), "Broker.LastOperation")
+	response, err := handler.lastOperationer.LastOperation(request.WithContext(ctx))
+	tracing.End(span, err)
+	if err != nil {
+		switch {
+		case errors.As(err, new(domain.ServiceInstanceNotFoundError)):
+			respond(w, http.StatusGone, EmptyJSON)
+		default:
+			respond(w, http.StatusInternalServerError, failure(req, err))
+		}
+		return
+	}
+
+	respond(w, http.StatusOK, struct {
+		State       domain.LastOperationState `json:"state"`
+		Description string                    `json:"description,omitempty"`
+	}{
+		State:       response.State,
+		Description: response.Description,
+	})
+}
+
+func (handler LastOperationHandler) Parse(req *http.Request) (domain.LastOperationRequest, error) {
+	instanceID, err := handler.idPolicy.pathParam(req, "instance_id")
+	if err != nil {
+		return domain.LastOperationRequest{}, err
+	}
+
+	query := req.URL.Query()
+
+	return domain.LastOperationRequest{
+		InstanceID: instanceID,
+		ServiceID:  query.Get("service_id"),
+		PlanID:     query.Get("plan_id"),
+		Operation:  query.Get("operation"),
+		RequestID:  requestid.FromContext(req.Context()),
+	}, nil
+}
+
+// acceptsIncomplete reports whether the platform accepts the operation
+// requested to finish asynchronously.
+func acceptsIncomplete(req *http.Request) bool {
+	return req.URL.Query().Get("accepts_incomplete") == "true"
+}
@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type LastOperationer struct {
+	WasCalledWith domain.LastOperationRequest
+	Response      domain.LastOperationResponse
+	Error         error
+}
+
+func (l *LastOperationer) LastOperation(request domain.LastOperationRequest) (domain.LastOperationResponse, error) {
+	l.WasCalledWith = request
+	return l.Response, l.Error
+}
+
+var _ = Describe("LastOperationHandler", func() {
+	var lastOperationer *LastOperationer
+	var handler handlers.LastOperationHandler
+
+	BeforeEach(func() {
+		lastOperationer = &LastOperationer{}
+		handler = handlers.NewLastOperationHandler(lastOperationer, handlers.IDPolicy{})
+	})
+
+	serve := func(url string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			panic(err)
+		}
+		request = routed(request)
+
+		handler.ServeHTTP(writer, request)
+		return writer
+	}
+
+	It("returns the state of the operation", func() {
+		lastOperationer.Response = domain.LastOperationResponse{State: domain.InProgress, Description: "50% done"}
+
+		writer := serve("/v2/service_instances/instance-id/last_operation?service_id=a-service&plan_id=a-plan&operation=an-operation")
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Body.String()).To(MatchJSON(`{"state":"in progress","description":"50% done"}`))
+		Expect(lastOperationer.WasCalledWith.InstanceID).To(Equal("instance-id"))
+		Expect(lastOperationer.WasCalledWith.ServiceID).To(Equal("a-service"))
+		Expect(lastOperationer.WasCalledWith.PlanID).To(Equal("a-plan"))
+		Expect(lastOperationer.WasCalledWith.Operation).To(Equal("an-operation"))
+	})
+
+	It("returns a 410 Gone when the service instance does not exist", func() {
+		lastOperationer.Error = domain.ServiceInstanceNotFoundError("gone")
+
+		writer := serve("/v2/service_instances/instance-id/last_operation")
+
+		Expect(writer.Code).To(Equal(http.StatusGone))
+		Expect(writer.Body.String()).To(MatchJSON(`{}`))
+	})
+
+	It("returns a 500 with the message when the broker fails", func() {
+		lastOperationer.Error = errors.New("backend unreachable")
+
+		writer := serve("/v2/service_instances/instance-id/last_operation")
+
+		Expect(writer.Code).To(Equal(http.StatusInternalServerError))
+		Expect(writer.Body.String()).To(MatchJSON(`{"description":"backend unreachable"}`))
+	})
+})
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+type maintenanceSwitch interface {
+	Enable(description string, retryAfter time.Duration)
+	Disable()
+	Status() (enabled bool, description string, retryAfter time.Duration)
+}
+
+type maintenanceState struct {
+	Enabled     *bool  `json:"enabled"`
+	Description string `json:"description"`
+	RetryAfter  int64  `json:"retry_after"`
+}
+
+// MaintenanceHandler reports the maintenance mode of the broker on GET
+// requests, and enables or disables it on PUT requests.
+type MaintenanceHandler struct {
+	maintenanceSwitch
+	bodyPolicy BodyPolicy
+}
+
+func NewMaintenanceHandler(maintenanceSwitch maintenanceSwitch, bodyPolicy BodyPolicy) MaintenanceHandler {
+	return MaintenanceHandler{
+		maintenanceSwitch: maintenanceSwitch,
+		bodyPolicy:        bodyPolicy,
+	}
+}
+
+func (handler MaintenanceHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == "PUT" {
+		var state maintenanceState
+		err := handler.bodyPolicy.decode(req, &state)
+		if err == nil {
+			err = validateMaintenanceState(state)
+		}
+		if err != nil {
+			respond(w, parseStatus(err), failure(req, err))
+			return
+		}
+
+		if *state.Enabled {
+			handler.Enable(state.Description, time.Duration(state.RetryAfter)*time.Second)
+		} else {
+			handler.Disable()
+		}
+	}
+
+	enabled, description, retryAfter := handler.Status()
+	respond(w, http.StatusOK, maintenanceState{
+		Enabled:     &enabled,
+		Description: description,
+		RetryAfter:  int64(retryAfter / time.Second),
+	})
+}
+
+func validateMaintenanceState(state maintenanceState) error {
+	if state.Enabled == nil {
+		return errors.New(`missing required field "enabled"`)
+	}
+
+	if state.RetryAfter < 0 {
+		return errors.New(`field "retry_after" must not be negative`)
+	}
+
+	return nil
+}
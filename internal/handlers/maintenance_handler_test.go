@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type FakeMaintenanceSwitch struct {
+	enabled     bool
+	description string
+	retryAfter  time.Duration
+}
+
+func (s *FakeMaintenanceSwitch) Enable(description string, retryAfter time.Duration) {
+	s.enabled = true
+	s.description = description
+	s.retryAfter = retryAfter
+}
+
+func (s *FakeMaintenanceSwitch) Disable() {
+	*s = FakeMaintenanceSwitch{}
+}
+
+func (s *FakeMaintenanceSwitch) Status() (bool, string, time.Duration) {
+	return s.enabled, s.description, s.retryAfter
+}
+
+var _ = Describe("MaintenanceHandler", func() {
+	var maintenanceSwitch *FakeMaintenanceSwitch
+	var handler handlers.MaintenanceHandler
+
+	BeforeEach(func() {
+		maintenanceSwitch = &FakeMaintenanceSwitch{}
+		handler = handlers.NewMaintenanceHandler(maintenanceSwitch, handlers.BodyPolicy{Strict: true})
+	})
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest(method, "/admin/maintenance", strings.NewReader(body))
+		if err != nil {
+			panic(err)
+		}
+
+		handler.ServeHTTP(writer, request)
+		return writer
+	}
+
+	It("reports the maintenance mode", func() {
+		writer := serve("GET", "")
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Body.String()).To(MatchJSON(`{"enabled":false,"description":"","retry_after":0}`))
+	})
+
+	It("enables and disables the maintenance mode", func() {
+		writer := serve("PUT", `{"enabled":true,"description":"upgrading","retry_after":600}`)
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Body.String()).To(MatchJSON(`{"enabled":true,"description":"upgrading","retry_after":600}`))
+		Expect(maintenanceSwitch.retryAfter).To(Equal(10 * time.Minute))
+
+		writer = serve("PUT", `{"enabled":false}`)
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(maintenanceSwitch.enabled).To(BeFalse())
+	})
+
+	It("returns a 400 when the body is invalid", func() {
+		for body, description := range map[string]string{
+			`{}`:                                `missing required field "enabled"`,
+			`{"enabled":true,"retry_after":-1}`: `field "retry_after" must not be negative`,
+			`{"enabled":"yes"}`:                 `field "enabled" must be a boolean`,
+			`{"enabled":true,"reason":"x"}`:     `unknown field "reason"`,
+		} {
+			writer := serve("PUT", body)
+
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":` + strconv.Quote(description) + `}`))
+		}
+		Expect(maintenanceSwitch.enabled).To(BeFalse())
+	})
+})
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultIDPattern matches IDs made of unreserved URL characters.
+var DefaultIDPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+
+// GUIDPattern matches IDs formatted as GUIDs, as generated by Cloud Foundry.
+var GUIDPattern = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)
+
+// IDPolicy describes the instance and binding IDs accepted in request
+// paths.
+type IDPolicy struct {
+	// MaxLength is the maximum length of an ID, in bytes. Zero means no
+	// limit.
+	MaxLength int
+
+	// Pattern must match the whole ID. A nil Pattern accepts any
+	// non-empty ID.
+	Pattern *regexp.Regexp
+}
+
+// pathParam returns the named path variable extracted by the router,
+// percent-decoded and checked against the policy. The router is expected
+// to match on the encoded path, so that an encoded slash stays part of the
+// ID instead of splitting the path.
+func (policy IDPolicy) pathParam(req *http.Request, name string) (string, error) {
+	value, err := url.PathUnescape(mux.Vars(req)[name])
+	if err != nil {
+		return "", fmt.Errorf("path parameter %q is not correctly percent-encoded", name)
+	}
+
+	if len(value) == 0 {
+		return "", fmt.Errorf("missing required path parameter %q", name)
+	}
+
+	if policy.MaxLength > 0 && len(value) > policy.MaxLength {
+		return "", fmt.Errorf("path parameter %q must not be longer than %d characters", name, policy.MaxLength)
+	}
+
+	if policy.Pattern != nil && !policy.Pattern.MatchString(value) {
+		return "", fmt.Errorf("path parameter %q has an invalid value %q", name, value)
+	}
+
+	return value, nil
+}
@@ -1,13 +1,12 @@
 package handlers
 
 import (
-	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"net/http"
-	"regexp"
 
 	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
 )
 
 type provisioner interface {
@@ -16,44 +15,59 @@ type provisioner interface {
 
 type ProvisionHandler struct {
 	provisioner
+	bodyPolicy BodyPolicy
+	idPolicy   IDPolicy
 }
 
-func NewProvisionHandler(provisioner provisioner) ProvisionHandler {
+func NewProvisionHandler(provisioner provisioner, bodyPolicy BodyPolicy, idPolicy IDPolicy) ProvisionHandler {
 	return ProvisionHandler{
 		provisioner: provisioner,
+		bodyPolicy:  bodyPolicy,
+		idPolicy:    idPolicy,
 	}
 }
 
 func (handler ProvisionHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, span := tracing.Start(req.Context(), "ProvisionHandler.Parse")
 	request, err := handler.Parse(req)
+	tracing.End(span, err)
 	if err != nil {
-		respond(w, http.StatusBadRequest, Failure{err.Error()})
+		respond(w, parseStatus(err), failure(req, err))
 		return
 	}
-	response, err := handler.provisioner.Provision(request)
+	ctx, span := tracing.Start(req.Context(), "Broker.Provision")
+	response, err := handler.provisioner.Provision(request.WithContext(ctx))
+	tracing.End(span, err)
 	if err != nil {
-		switch err.(type) {
-		case domain.ServiceInstanceAlreadyExistsError:
+		switch {
+		case errors.As(err, new(domain.ServiceInstanceAlreadyExistsError)):
 			respond(w, http.StatusConflict, EmptyJSON)
+		case errors.As(err, new(domain.ConcurrencyError)):
+			respond(w, http.StatusUnprocessableEntity, concurrencyFailure(req, err))
 		default:
-			respond(w, http.StatusInternalServerError, Failure{
-				Description: err.Error(),
-			})
+			respond(w, http.StatusInternalServerError, failure(req, err))
 		}
 		return
 	}
 
-	respond(w, http.StatusCreated, struct {
+	status := http.StatusCreated
+	if response.Async {
+		status = http.StatusAccepted
+	}
+
+	respond(w, status, struct {
 		DashboardURL string `json:"dashboard_url,omitempty"`
+		Operation    string `json:"operation,omitempty"`
 	}{
 		DashboardURL: response.DashboardURL,
+		Operation:    response.Operation,
 	})
 }
 
 func (handler ProvisionHandler) Parse(req *http.Request) (domain.ProvisionRequest, error) {
-	body, err := ioutil.ReadAll(req.Body)
+	instanceID, err := handler.idPolicy.pathParam(req, "instance_id")
 	if err != nil {
-		panic(err)
+		return domain.ProvisionRequest{}, err
 	}
 
 	var params struct {
@@ -61,25 +75,35 @@ func (handler ProvisionHandler) Parse(req *http.Request) (domain.ProvisionReques
 		PlanID           string `json:"plan_id"`
 		OrganizationGUID string `json:"organization_guid"`
 		SpaceGUID        string `json:"space_guid"`
+
+		// Fields of the Open Service Broker API that are not passed on
+		// to the broker, declared so that strict decoding accepts them.
+		Parameters      map[string]interface{} `json:"parameters"`
+		Context         map[string]interface{} `json:"context"`
+		MaintenanceInfo map[string]interface{} `json:"maintenance_info"`
 	}
-	err = json.Unmarshal(body, &params)
+	err = handler.bodyPolicy.decode(req, &params)
 	if err != nil {
-		return domain.ProvisionRequest{}, errors.New("request body must be a JSON object")
+		return domain.ProvisionRequest{}, err
 	}
 
-	expression := regexp.MustCompile(`^/v2/service_instances/(.*)$`)
-	instanceID := expression.FindStringSubmatch(req.URL.Path)[1]
-
-	if len(instanceID) == 0 || len(params.ServiceID) == 0 || len(params.PlanID) == 0 ||
-		len(params.OrganizationGUID) == 0 || len(params.SpaceGUID) == 0 {
-		return domain.ProvisionRequest{}, errors.New("missing required field")
+	err = missingField(
+		"service_id", params.ServiceID,
+		"plan_id", params.PlanID,
+		"organization_guid", params.OrganizationGUID,
+		"space_guid", params.SpaceGUID,
+	)
+	if err != nil {
+		return domain.ProvisionRequest{}, err
 	}
 
 	return domain.ProvisionRequest{
-		InstanceID:       instanceID,
-		ServiceID:        params.ServiceID,
-		PlanID:           params.PlanID,
-		OrganizationGUID: params.OrganizationGUID,
-		SpaceGUID:        params.SpaceGUID,
+		InstanceID:        instanceID,
+		ServiceID:         params.ServiceID,
+		PlanID:            params.PlanID,
+		OrganizationGUID:  params.OrganizationGUID,
+		SpaceGUID:         params.SpaceGUID,
+		AcceptsIncomplete: acceptsIncomplete(req),
+		RequestID:         requestid.FromContext(req.Context()),
 	}, nil
 }
@@ -20,6 +20,8 @@ type Provisioner struct {
 	WasCalled     bool
 	Error         error
 	DashboardURL  string
+	Async         bool
+	Operation     string
 }
 
 func NewProvisioner() *Provisioner {
@@ -31,6 +33,8 @@ func (p *Provisioner) Provision(req domain.ProvisionRequest) (domain.ProvisionRe
 	p.WasCalled = true
 	return domain.ProvisionResponse{
 		DashboardURL: p.DashboardURL,
+		Async:        p.Async,
+		Operation:    p.Operation,
 	}, p.Error
 }
 
@@ -40,7 +44,7 @@ var _ = Describe("Provision Handler", func() {
 
 	BeforeEach(func() {
 		provisioner = NewProvisioner()
-		handler = handlers.NewProvisionHandler(provisioner)
+		handler = handlers.NewProvisionHandler(provisioner, handlers.BodyPolicy{}, handlers.IDPolicy{})
 	})
 
 	Context("when dashboard URL is not specified", func() {
@@ -60,6 +64,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -74,7 +79,7 @@ var _ = Describe("Provision Handler", func() {
 				ServiceID:        "my-service-id",
 				OrganizationGUID: "my-organization-guid",
 				SpaceGUID:        "my-space-guid",
-			}))
+			}.WithContext(request.Context())))
 		})
 	})
 
@@ -99,6 +104,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -115,7 +121,30 @@ var _ = Describe("Provision Handler", func() {
 				ServiceID:        "my-service-id",
 				OrganizationGUID: "my-organization-guid",
 				SpaceGUID:        "my-space-guid",
-			}))
+			}.WithContext(request.Context())))
+		})
+	})
+
+	Context("when the provisioner finishes asynchronously", func() {
+		BeforeEach(func() {
+			provisioner.Async = true
+			provisioner.Operation = "an-operation"
+		})
+
+		It("returns a 202 with the operation", func() {
+			writer := httptest.NewRecorder()
+			body := `{"service_id":"a-service","plan_id":"a-plan","organization_guid":"an-org","space_guid":"a-space"}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/a-guid?accepts_incomplete=true", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(provisioner.WasCalledWith.AcceptsIncomplete).To(BeTrue())
+			Expect(writer.Code).To(Equal(http.StatusAccepted))
+			Expect(writer.Body.String()).To(MatchJSON(`{"operation":"an-operation"}`))
 		})
 	})
 
@@ -140,6 +169,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -171,6 +201,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -181,6 +212,38 @@ var _ = Describe("Provision Handler", func() {
 		})
 	})
 
+	Context("when another operation is in progress on the service instance", func() {
+		BeforeEach(func() {
+			provisioner.Error = domain.ConcurrencyError("an operation is in progress")
+		})
+
+		It("returns a 422 and a ConcurrencyRequest error", func() {
+			writer := httptest.NewRecorder()
+			reqBody, err := json.Marshal(map[string]string{
+				"service_id":        "my-service-id",
+				"plan_id":           "my-plan-id",
+				"organization_guid": "my-organization-guid",
+				"space_guid":        "my-space-guid",
+			})
+			if err != nil {
+				panic(err)
+			}
+
+			request, err := http.NewRequest("PUT", "/v2/service_instances/a-busy-guid", bytes.NewBuffer(reqBody))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(writer.Header()["Content-Type"]).To(Equal([]string{"application/json"}))
+
+			Expect(writer.Body.String()).To(MatchJSON(`{"error":"ConcurrencyRequest","description":"an operation is in progress"}`))
+		})
+	})
+
 	Context("when the request body is not valid JSON", func() {
 		It("should not call the provisioner", func() {
 			writer := httptest.NewRecorder()
@@ -189,6 +252,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -202,6 +266,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -224,6 +289,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -237,6 +303,7 @@ var _ = Describe("Provision Handler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -250,4 +317,104 @@ var _ = Describe("Provision Handler", func() {
 			Expect(msg.Description).To(ContainSubstring("missing required field"))
 		})
 	})
+
+	Context("when the request body has a field of the wrong type", func() {
+		It("should return a 400 naming the field", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":42,"organization_guid":"an-org","space_guid":"a-space"}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/a-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(provisioner.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"field \"plan_id\" must be a string"}`))
+		})
+	})
+
+	Context("when the request body is followed by trailing data", func() {
+		It("should return a 400 and an error message", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","organization_guid":"an-org","space_guid":"a-space"} {}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/a-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(provisioner.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"request body must be a single JSON object"}`))
+		})
+	})
+
+	Context("when the request body exceeds the maximum size", func() {
+		BeforeEach(func() {
+			handler = handlers.NewProvisionHandler(provisioner, handlers.BodyPolicy{MaxBytes: 16}, handlers.IDPolicy{})
+		})
+
+		It("should return a 413 and an error message", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","organization_guid":"an-org","space_guid":"a-space"}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/a-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(provisioner.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"request body must not exceed 16 bytes"}`))
+		})
+	})
+
+	Context("when strict decoding is enabled", func() {
+		BeforeEach(func() {
+			handler = handlers.NewProvisionHandler(provisioner, handlers.BodyPolicy{Strict: true}, handlers.IDPolicy{})
+		})
+
+		It("should return a 400 naming an unknown field", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","organization_guid":"an-org","space_guid":"a-space","plan":"typo"}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/a-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(provisioner.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"unknown field \"plan\""}`))
+		})
+
+		It("should accept a body with the fields of the service broker API", func() {
+			writer := httptest.NewRecorder()
+
+			body := `{"service_id":"a-service","plan_id":"a-plan","organization_guid":"an-org","space_guid":"a-space","parameters":{},"context":{"platform":"cloudfoundry"},"maintenance_info":{"version":"1.0.0"}}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/a-guid", strings.NewReader(body))
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(provisioner.WasCalled).To(BeTrue())
+			Expect(writer.Code).To(Equal(http.StatusCreated))
+		})
+	})
 })
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
 )
 
 type Failure struct {
@@ -11,6 +14,29 @@ type Failure struct {
 
 var EmptyJSON = map[string]interface{}{}
 
+// failure describes err, including the ID of the request, if any, so that
+// the platform's logs can be correlated with the broker's.
+func failure(req *http.Request, err error) Failure {
+	description := err.Error()
+	if id := requestid.FromContext(req.Context()); id != "" {
+		description = fmt.Sprintf("%s (request ID: %s)", description, id)
+	}
+
+	return Failure{Description: description}
+}
+
+// concurrencyFailure describes a ConcurrencyError, naming the service
+// broker API error asking the platform to retry the request later.
+func concurrencyFailure(req *http.Request, err error) interface{} {
+	return struct {
+		Error       string `json:"error"`
+		Description string `json:"description"`
+	}{
+		Error:       "ConcurrencyRequest",
+		Description: failure(req, err).Description,
+	}
+}
+
 func respond(w http.ResponseWriter, code int, response interface{}) {
 	body, err := json.Marshal(response)
 	if err != nil {
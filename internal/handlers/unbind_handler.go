@@ -3,9 +3,10 @@ package handlers
 import (
 	"errors"
 	"net/http"
-	"regexp"
 
 	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
 )
 
 type unbinder interface {
@@ -14,30 +15,36 @@ type unbinder interface {
 
 type UnbindHandler struct {
 	unbinder
+	idPolicy IDPolicy
 }
 
-func NewUnbindHandler(unbinder unbinder) UnbindHandler {
+func NewUnbindHandler(unbinder unbinder, idPolicy IDPolicy) UnbindHandler {
 	return UnbindHandler{
 		unbinder: unbinder,
+		idPolicy: idPolicy,
 	}
 }
 
 func (handler UnbindHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, span := tracing.Start(req.Context(), "UnbindHandler.Parse")
 	request, err := handler.Parse(req)
+	tracing.End(span, err)
 	if err != nil {
-		respond(w, http.StatusBadRequest, Failure{err.Error()})
+		respond(w, http.StatusBadRequest, failure(req, err))
 		return
 	}
 
-	err = handler.unbinder.Unbind(request)
+	ctx, span := tracing.Start(req.Context(), "Broker.Unbind")
+	err = handler.unbinder.Unbind(request.WithContext(ctx))
+	tracing.End(span, err)
 	if err != nil {
-		switch err.(type) {
-		case domain.ServiceBindingNotFoundError:
+		switch {
+		case errors.As(err, new(domain.ServiceBindingNotFoundError)):
 			respond(w, http.StatusGone, EmptyJSON)
+		case errors.As(err, new(domain.ConcurrencyError)):
+			respond(w, http.StatusUnprocessableEntity, concurrencyFailure(req, err))
 		default:
-			respond(w, http.StatusInternalServerError, Failure{
-				Description: err.Error(),
-			})
+			respond(w, http.StatusInternalServerError, failure(req, err))
 		}
 		return
 	}
@@ -46,8 +53,15 @@ func (handler UnbindHandler) ServeHTTP(w http.ResponseWriter, req *http.Request)
 }
 
 func (handler UnbindHandler) Parse(req *http.Request) (domain.UnbindRequest, error) {
-	expression := regexp.MustCompile(`^/v2/service_instances/(.*)/service_bindings/(.*)$`)
-	matches := expression.FindStringSubmatch(req.URL.Path)
+	instanceID, err := handler.idPolicy.pathParam(req, "instance_id")
+	if err != nil {
+		return domain.UnbindRequest{}, err
+	}
+
+	bindingID, err := handler.idPolicy.pathParam(req, "binding_id")
+	if err != nil {
+		return domain.UnbindRequest{}, err
+	}
 
 	serviceIDValues := req.URL.Query()["service_id"]
 	planIDValues := req.URL.Query()["plan_id"]
@@ -57,9 +71,10 @@ func (handler UnbindHandler) Parse(req *http.Request) (domain.UnbindRequest, err
 	}
 
 	return domain.UnbindRequest{
-		BindingID:  matches[2],
-		InstanceID: matches[1],
+		BindingID:  bindingID,
+		InstanceID: instanceID,
 		ServiceID:  serviceIDValues[0],
 		PlanID:     planIDValues[0],
+		RequestID:  requestid.FromContext(req.Context()),
 	}, nil
 }
@@ -37,7 +37,7 @@ var _ = Describe("UnbindHandler", func() {
 
 	BeforeEach(func() {
 		unbinder = NewUnbinder()
-		handler = handlers.NewUnbindHandler(unbinder)
+		handler = handlers.NewUnbindHandler(unbinder, handlers.IDPolicy{})
 	})
 
 	It("calls the binder Unbind method with the correct values", func() {
@@ -51,6 +51,7 @@ var _ = Describe("UnbindHandler", func() {
 		if err != nil {
 			panic(err)
 		}
+		request = routed(request)
 
 		handler.ServeHTTP(writer, request)
 
@@ -59,7 +60,7 @@ var _ = Describe("UnbindHandler", func() {
 			InstanceID: "service-instance-id",
 			ServiceID:  "the-sshfs-service-id",
 			PlanID:     "the-1gb-plan-id",
-		}))
+		}.WithContext(request.Context())))
 	})
 
 	Context("when the unbinder succeeds", func() {
@@ -71,6 +72,7 @@ var _ = Describe("UnbindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -94,6 +96,7 @@ var _ = Describe("UnbindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			unbinder.UnbindError = domain.ServiceBindingNotFoundError(
 				("that binding doesn't exist!"))
@@ -120,6 +123,7 @@ var _ = Describe("UnbindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			unbinder.UnbindError = errors.New("my database failed somehow!")
 
@@ -146,6 +150,7 @@ var _ = Describe("UnbindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -160,6 +165,7 @@ var _ = Describe("UnbindHandler", func() {
 			if err != nil {
 				panic(err)
 			}
+			request = routed(request)
 
 			handler.ServeHTTP(writer, request)
 
@@ -173,4 +179,45 @@ var _ = Describe("UnbindHandler", func() {
 		})
 	})
 
+	Context("when the IDs are percent-encoded", func() {
+		It("calls the unbinder with the decoded IDs", func() {
+			writer := httptest.NewRecorder()
+
+			url := "/v2/service_instances/instance%3Aid/service_bindings/binding%2Fid?plan_id=some-plan-id&service_id=some-service-id"
+			request, err := http.NewRequest("DELETE", url, nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusOK))
+			Expect(unbinder.WasCalledWith.InstanceID).To(Equal("instance:id"))
+			Expect(unbinder.WasCalledWith.BindingID).To(Equal("binding/id"))
+		})
+	})
+
+	Context("when the binding ID does not match the ID policy", func() {
+		BeforeEach(func() {
+			handler = handlers.NewUnbindHandler(unbinder, handlers.IDPolicy{Pattern: handlers.DefaultIDPattern})
+		})
+
+		It("should return a 400 error naming the parameter", func() {
+			writer := httptest.NewRecorder()
+
+			url := "/v2/service_instances/instance-id/service_bindings/binding%2Fid?plan_id=some-plan-id&service_id=some-service-id"
+			request, err := http.NewRequest("DELETE", url, nil)
+			if err != nil {
+				panic(err)
+			}
+			request = routed(request)
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(unbinder.WasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusBadRequest))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"path parameter \"binding_id\" has an invalid value \"binding/id\""}`))
+		})
+	})
 })
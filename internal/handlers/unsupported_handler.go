@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// UnsupportedHandler answers requests for an operation the broker does
+// not implement with a 501 Not Implemented response, rather than
+// pretending that the operation succeeded.
+type UnsupportedHandler struct {
+	operation string
+}
+
+func NewUnsupportedHandler(operation string) UnsupportedHandler {
+	return UnsupportedHandler{
+		operation: operation,
+	}
+}
+
+func (handler UnsupportedHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	err := fmt.Errorf("the service broker does not support the %s operation", handler.operation)
+	respond(w, http.StatusNotImplemented, failure(req, err))
+}
@@ -0,0 +1,26 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UnsupportedHandler", func() {
+	It("returns a 501 naming the operation", func() {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest("PUT", "/v2/service_instances/banana/service_bindings/apple", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		handlers.NewUnsupportedHandler("bind").ServeHTTP(writer, request)
+
+		Expect(writer.Code).To(Equal(http.StatusNotImplemented))
+		Expect(writer.Body.String()).To(MatchJSON(`{"description":"the service broker does not support the bind operation"}`))
+	})
+})
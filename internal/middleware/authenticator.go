@@ -1,59 +1,97 @@
 package middleware
 
 import (
-	"encoding/base64"
+	"crypto/subtle"
 	"net/http"
-	"regexp"
-	"strings"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// challenge is sent in the WWW-Authenticate header of 401 responses.
+const challenge = `Basic realm="service broker", charset="UTF-8"`
+
 type Credentialer interface {
 	Credentials() (string, string)
 }
 
+// MultiCredentialer is implemented by credentialers accepting several
+// credential pairs, for example while credentials are being rotated.
+type MultiCredentialer interface {
+	AllCredentials() []domain.Credentials
+}
+
 type Authenticator struct {
-	Handler      http.Handler
-	credentialer Credentialer
+	Handler       http.Handler
+	credentialer  Credentialer
+	verifications *VerificationCache
 }
 
-func NewAuthenticator(handler http.Handler, credentialer Credentialer) http.Handler {
+func NewAuthenticator(handler http.Handler, credentialer Credentialer, verifications *VerificationCache) http.Handler {
 	return Authenticator{
-		Handler:      handler,
-		credentialer: credentialer,
+		Handler:       handler,
+		credentialer:  credentialer,
+		verifications: verifications,
 	}
 }
 
 func (a Authenticator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	header := req.Header.Get("Authorization")
-	expression := regexp.MustCompile(`(?i)basic (.*)`)
-	regexMatches := expression.FindStringSubmatch(header)
-	if len(regexMatches) != 2 {
+	_, span := tracing.Start(req.Context(), "Authenticator")
+	authenticated := a.authenticate(req)
+	span.SetAttributes(attribute.Bool("envoy.authenticated", authenticated))
+	span.End()
+
+	if !authenticated {
 		a.Fail(w)
 		return
 	}
 
-	encodedAuth := regexMatches[1]
-	decodedAuth, err := base64.StdEncoding.DecodeString(encodedAuth)
-	if err != nil {
-		a.Fail(w)
-		return
+	a.Handler.ServeHTTP(w, req)
+}
+
+// authenticate reports whether the Basic Auth credentials of the request,
+// parsed as described in RFC 7617, match any of the accepted pairs. Every
+// pair is compared in constant time, so the time taken does not reveal
+// which pair, or how much of it, matched. Password hashes are only
+// verified for pairs whose username matches, since verifying them is
+// deliberately slow.
+func (a Authenticator) authenticate(req *http.Request) bool {
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		return false
 	}
 
-	auth := strings.Split(string(decodedAuth), ":")
-	if len(auth) != 2 {
-		a.Fail(w)
-		return
+	givenUsername := digest(username)
+	givenCredentials := digest(username, password)
+
+	var matched bool
+	for _, credentials := range a.accepted() {
+		if credentials.PasswordHash == "" {
+			expected := digest(credentials.Username, credentials.Password)
+			matched = subtle.ConstantTimeCompare(givenCredentials[:], expected[:]) == 1 || matched
+			continue
+		}
+
+		expected := digest(credentials.Username)
+		if subtle.ConstantTimeCompare(givenUsername[:], expected[:]) == 1 {
+			matched = a.verifications.verify(credentials.PasswordHash, username, password) || matched
+		}
 	}
 
-	username, password := a.credentialer.Credentials()
-	if username != auth[0] || password != auth[1] {
-		a.Fail(w)
-		return
+	return matched
+}
+
+func (a Authenticator) accepted() []domain.Credentials {
+	if multi, ok := a.credentialer.(MultiCredentialer); ok {
+		return multi.AllCredentials()
 	}
 
-	a.Handler.ServeHTTP(w, req)
+	username, password := a.credentialer.Credentials()
+	return []domain.Credentials{{Username: username, Password: password}}
 }
 
 func (a Authenticator) Fail(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", challenge)
 	w.WriteHeader(http.StatusUnauthorized)
 }
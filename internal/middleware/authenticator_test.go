@@ -4,7 +4,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/pivotal-cf-experimental/envoy/domain"
 	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/bcrypt"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,6 +25,28 @@ func (c Credentialer) Credentials() (string, string) {
 	return "username", "password"
 }
 
+type MultiCredentialer struct {
+	Credentialer
+}
+
+func (c MultiCredentialer) AllCredentials() []domain.Credentials {
+	return []domain.Credentials{
+		{Username: "old-username", Password: "old-password"},
+		{Username: "new-username", Password: "new:password"},
+	}
+}
+
+type HashingCredentialer struct {
+	Credentialer
+	Hash string
+}
+
+func (c HashingCredentialer) AllCredentials() []domain.Credentials {
+	return []domain.Credentials{
+		{Username: "username", PasswordHash: c.Hash},
+	}
+}
+
 var _ = Describe("Authenticator", func() {
 	Describe("ServeHTTP", func() {
 		var wasCalled bool
@@ -35,7 +62,7 @@ var _ = Describe("Authenticator", func() {
 				w.WriteHeader(http.StatusTeapot)
 			})
 			credentialer := NewCredentialer()
-			authenticator = middleware.NewAuthenticator(handler, credentialer)
+			authenticator = middleware.NewAuthenticator(handler, credentialer, middleware.NewVerificationCache())
 
 			writer = httptest.NewRecorder()
 			request, err = http.NewRequest("GET", "/foo", nil)
@@ -58,6 +85,114 @@ var _ = Describe("Authenticator", func() {
 
 			Expect(wasCalled).To(BeFalse())
 			Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+			Expect(writer.Header().Get("WWW-Authenticate")).To(Equal(`Basic realm="service broker", charset="UTF-8"`))
+		})
+
+		It("returns a 401 when the password is wrong", func() {
+			request.SetBasicAuth("username", "passwordd")
+
+			authenticator.ServeHTTP(writer, request)
+
+			Expect(wasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("returns a 401 when the credentials are not Base64 encoded", func() {
+			request.Header.Set("Authorization", "Basic username:password")
+
+			authenticator.ServeHTTP(writer, request)
+
+			Expect(wasCalled).To(BeFalse())
+			Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("does not accept credentials moved across the colon", func() {
+			request.SetBasicAuth("username:", "password")
+
+			authenticator.ServeHTTP(writer, request)
+
+			Expect(wasCalled).To(BeFalse())
+		})
+
+		It("accepts the scheme in any case", func() {
+			request.SetBasicAuth("username", "password")
+			request.Header.Set("Authorization", "basic "+request.Header.Get("Authorization")[len("Basic "):])
+
+			authenticator.ServeHTTP(writer, request)
+
+			Expect(wasCalled).To(BeTrue())
+		})
+
+		Context("when the credentialer returns password hashes", func() {
+			BeforeEach(func() {
+				hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+				Expect(err).NotTo(HaveOccurred())
+
+				handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					wasCalled = true
+				})
+				authenticator = middleware.NewAuthenticator(handler, HashingCredentialer{Hash: string(hash)}, middleware.NewVerificationCache())
+			})
+
+			It("verifies the password against the hash", func() {
+				request.SetBasicAuth("username", "password")
+				authenticator.ServeHTTP(writer, request)
+				Expect(wasCalled).To(BeTrue())
+			})
+
+			It("returns a 401 when the password does not match", func() {
+				request.SetBasicAuth("username", "$2a$04$")
+				authenticator.ServeHTTP(writer, request)
+				Expect(wasCalled).To(BeFalse())
+				Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("when the credentialer accepts several credential pairs", func() {
+			BeforeEach(func() {
+				handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					wasCalled = true
+				})
+				authenticator = middleware.NewAuthenticator(handler, MultiCredentialer{}, middleware.NewVerificationCache())
+			})
+
+			It("accepts any of the pairs", func() {
+				request.SetBasicAuth("old-username", "old-password")
+				authenticator.ServeHTTP(writer, request)
+				Expect(wasCalled).To(BeTrue())
+
+				wasCalled = false
+				request.SetBasicAuth("new-username", "new:password")
+				authenticator.ServeHTTP(writer, request)
+				Expect(wasCalled).To(BeTrue())
+			})
+
+			It("does not accept mixed pairs, or the single pair", func() {
+				request.SetBasicAuth("old-username", "new:password")
+				authenticator.ServeHTTP(writer, request)
+				Expect(wasCalled).To(BeFalse())
+
+				request.SetBasicAuth("username", "password")
+				authenticator.ServeHTTP(writer, request)
+				Expect(wasCalled).To(BeFalse())
+				Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		It("records a span when the request is traced", func() {
+			exporter := tracetest.NewInMemoryExporter()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			ctx, parent := provider.Tracer("test").Start(request.Context(), "request")
+			request = request.WithContext(ctx)
+
+			authenticator.ServeHTTP(writer, request)
+			parent.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name).To(Equal("Authenticator"))
+			Expect(spans[0].Parent.SpanID()).To(Equal(parent.SpanContext().SpanID()))
+			Expect(spans[0].Attributes).To(ContainElement(attribute.Bool("envoy.authenticated", false)))
 		})
 	})
 })
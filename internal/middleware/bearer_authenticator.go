@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pivotal-cf-experimental/envoy/bearer"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type tokenVerifier interface {
+	Verify(token string) (bearer.Claims, error)
+}
+
+// BearerAuthenticator authenticates requests with the bearer token given
+// in their Authorization header, as described in RFC 6750. The claims of
+// verified tokens are added to the request context.
+type BearerAuthenticator struct {
+	Handler  http.Handler
+	verifier tokenVerifier
+}
+
+func NewBearerAuthenticator(handler http.Handler, verifier tokenVerifier) http.Handler {
+	return BearerAuthenticator{
+		Handler:  handler,
+		verifier: verifier,
+	}
+}
+
+func (a BearerAuthenticator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, span := tracing.Start(req.Context(), "Authenticator")
+	claims, err := a.authenticate(req)
+	span.SetAttributes(attribute.Bool("envoy.authenticated", err == nil))
+	span.End()
+
+	if err != nil {
+		a.Fail(w, err)
+		return
+	}
+
+	a.Handler.ServeHTTP(w, req.WithContext(bearer.NewContext(req.Context(), claims)))
+}
+
+func (a BearerAuthenticator) authenticate(req *http.Request) (bearer.Claims, error) {
+	header := req.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return bearer.Claims{}, errMissingToken
+	}
+
+	return a.verifier.Verify(strings.TrimSpace(header[len("Bearer "):]))
+}
+
+var errMissingToken = errors.New("bearer: missing token")
+
+// Fail responds with a 401 and a challenge describing err, or a 403 when
+// the token lacks a required scope. The challenge never includes details
+// of why a token is invalid, as they are only useful to an attacker.
+func (a BearerAuthenticator) Fail(w http.ResponseWriter, err error) {
+	if scope, ok := err.(bearer.InsufficientScopeError); ok {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="service broker", error="insufficient_scope", scope=%q`, string(scope)))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err == errMissingToken {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="service broker"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="service broker", error="invalid_token"`)
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+}
@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/bearer"
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type TokenVerifier struct {
+	Token string
+	Err   error
+}
+
+func (v TokenVerifier) Verify(token string) (bearer.Claims, error) {
+	if token != v.Token {
+		return bearer.Claims{}, bearer.InvalidTokenError("signature is invalid")
+	}
+
+	return bearer.Claims{Subject: "some-user"}, v.Err
+}
+
+var _ = Describe("BearerAuthenticator", func() {
+	var verifier TokenVerifier
+	var claims bearer.Claims
+	var wasCalled bool
+	var writer *httptest.ResponseRecorder
+	var request *http.Request
+
+	BeforeEach(func() {
+		var err error
+		verifier = TokenVerifier{Token: "a-token"}
+		wasCalled = false
+		claims = bearer.Claims{}
+
+		writer = httptest.NewRecorder()
+		request, err = http.NewRequest("GET", "/foo", nil)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	serve := func() {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			wasCalled = true
+			claims, _ = bearer.FromContext(req.Context())
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		middleware.NewBearerAuthenticator(handler, verifier).ServeHTTP(writer, request)
+	}
+
+	It("delegates to the handler with the claims of a valid token", func() {
+		request.Header.Set("Authorization", "Bearer a-token")
+
+		serve()
+
+		Expect(wasCalled).To(BeTrue())
+		Expect(writer.Code).To(Equal(http.StatusTeapot))
+		Expect(claims.Subject).To(Equal("some-user"))
+	})
+
+	It("accepts the scheme in any case", func() {
+		request.Header.Set("Authorization", "bearer a-token")
+
+		serve()
+
+		Expect(wasCalled).To(BeTrue())
+	})
+
+	It("returns a 401 with a challenge when no token is given", func() {
+		request.SetBasicAuth("username", "password")
+
+		serve()
+
+		Expect(wasCalled).To(BeFalse())
+		Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+		Expect(writer.Header().Get("WWW-Authenticate")).To(Equal(`Bearer realm="service broker"`))
+	})
+
+	It("returns a 401 when the token is invalid", func() {
+		request.Header.Set("Authorization", "Bearer another-token")
+
+		serve()
+
+		Expect(wasCalled).To(BeFalse())
+		Expect(writer.Code).To(Equal(http.StatusUnauthorized))
+		Expect(writer.Header().Get("WWW-Authenticate")).To(Equal(`Bearer realm="service broker", error="invalid_token"`))
+	})
+
+	It("returns a 403 when the token lacks a required scope", func() {
+		verifier.Err = bearer.InsufficientScopeError("my-broker.admin")
+		request.Header.Set("Authorization", "Bearer a-token")
+
+		serve()
+
+		Expect(wasCalled).To(BeFalse())
+		Expect(writer.Code).To(Equal(http.StatusForbidden))
+		Expect(writer.Header().Get("WWW-Authenticate")).To(Equal(`Bearer realm="service broker", error="insufficient_scope", scope="my-broker.admin"`))
+	})
+})
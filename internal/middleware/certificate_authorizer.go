@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// CertificateAuthorizer only lets requests through when the verified
+// client certificate presented over TLS is authorized by the given
+// function.
+type CertificateAuthorizer struct {
+	Handler   http.Handler
+	authorize func(*x509.Certificate) bool
+}
+
+func NewCertificateAuthorizer(handler http.Handler, authorize func(*x509.Certificate) bool) http.Handler {
+	return CertificateAuthorizer{
+		Handler:   handler,
+		authorize: authorize,
+	}
+}
+
+func (a CertificateAuthorizer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		fail(w, req, http.StatusForbidden, "a verified client certificate is required")
+		return
+	}
+
+	if !a.authorize(req.TLS.VerifiedChains[0][0]) {
+		fail(w, req, http.StatusForbidden, "the client certificate is not authorized for this operation")
+		return
+	}
+
+	a.Handler.ServeHTTP(w, req)
+}
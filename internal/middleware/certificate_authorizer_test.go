@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CertificateAuthorizer", func() {
+	var wasCalled bool
+	var authorizer http.Handler
+	var writer *httptest.ResponseRecorder
+	var request *http.Request
+
+	BeforeEach(func() {
+		var err error
+		wasCalled = false
+		handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			wasCalled = true
+		})
+		authorizer = middleware.NewCertificateAuthorizer(handler, func(certificate *x509.Certificate) bool {
+			return certificate.Subject.CommonName == "cloud-controller"
+		})
+
+		writer = httptest.NewRecorder()
+		request, err = http.NewRequest("GET", "/foo", nil)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	verifiedWith := func(commonName string) *tls.ConnectionState {
+		return &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}},
+		}
+	}
+
+	It("delegates to the handler when the certificate is authorized", func() {
+		request.TLS = verifiedWith("cloud-controller")
+
+		authorizer.ServeHTTP(writer, request)
+
+		Expect(wasCalled).To(BeTrue())
+	})
+
+	It("returns a 403 when the certificate is not authorized", func() {
+		request.TLS = verifiedWith("someone-else")
+
+		authorizer.ServeHTTP(writer, request)
+
+		Expect(wasCalled).To(BeFalse())
+		Expect(writer.Code).To(Equal(http.StatusForbidden))
+		Expect(writer.Body.String()).To(MatchJSON(`{"description":"the client certificate is not authorized for this operation"}`))
+	})
+
+	It("returns a 403 when no verified certificate was presented", func() {
+		request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "cloud-controller"}}}}
+
+		authorizer.ServeHTTP(writer, request)
+
+		Expect(wasCalled).To(BeFalse())
+		Expect(writer.Code).To(Equal(http.StatusForbidden))
+		Expect(writer.Body.String()).To(MatchJSON(`{"description":"a verified client certificate is required"}`))
+	})
+})
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// drainRetryAfter is the delay clients are asked to wait before retrying
+// requests refused while draining.
+const drainRetryAfter = 5 * time.Second
+
+// Gate tracks the mutating requests in flight, and refuses new ones once
+// it has been closed. It is safe for concurrent use.
+type Gate struct {
+	mutex    sync.Mutex
+	closed   bool
+	inFlight int
+	drained  chan struct{}
+}
+
+func NewGate() *Gate {
+	return &Gate{
+		drained: make(chan struct{}),
+	}
+}
+
+func (g *Gate) enter() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.closed {
+		return false
+	}
+
+	g.inFlight++
+	return true
+}
+
+func (g *Gate) leave() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.inFlight--
+	if g.closed && g.inFlight == 0 {
+		close(g.drained)
+	}
+}
+
+// Close refuses new mutating requests. It can be called more than once.
+func (g *Gate) Close() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.closed {
+		return
+	}
+
+	g.closed = true
+	if g.inFlight == 0 {
+		close(g.drained)
+	}
+}
+
+// Closed reports whether the gate has been closed.
+func (g *Gate) Closed() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.closed
+}
+
+// Wait closes the gate, and waits until the mutating requests in flight
+// have been served or the context is done.
+func (g *Gate) Wait(ctx context.Context) error {
+	g.Close()
+
+	select {
+	case <-g.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drainer answers mutating requests with a 503 Service Unavailable
+// response once its gate has been closed, and tracks those in flight so
+// that they can be waited for. Safe requests, such as those for the
+// catalog, are always served.
+type Drainer struct {
+	Handler http.Handler
+	gate    *Gate
+}
+
+func NewDrainer(handler http.Handler, gate *Gate) http.Handler {
+	return Drainer{
+		Handler: handler,
+		gate:    gate,
+	}
+}
+
+func (d Drainer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == "GET" || req.Method == "HEAD" {
+		d.Handler.ServeHTTP(w, req)
+		return
+	}
+
+	if !d.gate.enter() {
+		retryAfter(w, drainRetryAfter)
+		fail(w, req, http.StatusServiceUnavailable, "the service broker is shutting down")
+		return
+	}
+	defer d.gate.leave()
+
+	d.Handler.ServeHTTP(w, req)
+}
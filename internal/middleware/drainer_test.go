@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drainer", func() {
+	var gate *middleware.Gate
+	var started, release chan struct{}
+	var drainer http.Handler
+
+	BeforeEach(func() {
+		gate = middleware.NewGate()
+		started = make(chan struct{}, 1)
+		release = make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == "PUT" {
+				started <- struct{}{}
+				<-release
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+		drainer = middleware.NewDrainer(handler, gate)
+	})
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest(method, "/foo", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		drainer.ServeHTTP(writer, request)
+		return writer
+	}
+
+	It("serves requests while the gate is open", func() {
+		Expect(serve("DELETE").Code).To(Equal(http.StatusCreated))
+	})
+
+	It("refuses mutating requests once the gate is closed, but still serves safe requests", func() {
+		gate.Close()
+
+		writer := serve("DELETE")
+		Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(writer.Header().Get("Retry-After")).To(Equal("5"))
+		Expect(writer.Body.String()).To(MatchJSON(`{"description":"the service broker is shutting down"}`))
+
+		Expect(serve("GET").Code).To(Equal(http.StatusCreated))
+	})
+
+	It("waits for the requests in flight", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			Expect(serve("PUT").Code).To(Equal(http.StatusCreated))
+			close(done)
+		}()
+		<-started
+
+		waited := make(chan error, 1)
+		go func() {
+			waited <- gate.Wait(context.Background())
+		}()
+		Consistently(waited).ShouldNot(Receive())
+
+		close(release)
+		Eventually(waited).Should(Receive(BeNil()))
+		<-done
+	})
+
+	It("stops waiting when the context is done", func() {
+		go serve("PUT")
+		<-started
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		Expect(gate.Wait(ctx)).To(Equal(context.DeadlineExceeded))
+	})
+})
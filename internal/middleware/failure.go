@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+)
+
+// fail responds with the given status and a service broker API error
+// body holding the description, including the ID of the request, if any.
+func fail(w http.ResponseWriter, req *http.Request, status int, description string) {
+	if id := requestid.FromContext(req.Context()); id != "" {
+		description = fmt.Sprintf("%s (request ID: %s)", description, id)
+	}
+
+	body, _ := json.Marshal(struct {
+		Description string `json:"description"`
+	}{description})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/redact"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Logger struct {
+	Handler  http.Handler
+	logger   *slog.Logger
+	redactor redact.Redactor
+}
+
+func NewLogger(handler http.Handler, logger *slog.Logger, redactor redact.Redactor) http.Handler {
+	return Logger{
+		Handler:  handler,
+		logger:   logger,
+		redactor: redactor,
+	}
+}
+
+func (l Logger) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	recorder := newStatusRecorder(w)
+
+	debug := l.logger.Enabled(req.Context(), slog.LevelDebug)
+	var requestBody limitedBuffer
+	if debug && req.Body != nil {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(req.Body, &requestBody), req.Body}
+	}
+
+	l.Handler.ServeHTTP(recorder, req)
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("route", routeTemplate(req)),
+		slog.Int("status", recorder.status),
+		slog.Duration("latency", time.Since(start)),
+	}
+
+	if id := requestid.FromContext(req.Context()); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+
+	vars := mux.Vars(req)
+	if instanceID, ok := vars["instance_id"]; ok {
+		attrs = append(attrs, slog.String("instance_id", instanceID))
+	}
+
+	if bindingID, ok := vars["binding_id"]; ok {
+		attrs = append(attrs, slog.String("binding_id", bindingID))
+	}
+
+	if spanContext := trace.SpanContextFromContext(req.Context()); spanContext.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+
+	if version := req.Header.Get("X-Broker-API-Version"); version != "" {
+		attrs = append(attrs, slog.String("api_version", version))
+	}
+
+	if identity := req.Header.Get("X-Broker-API-Originating-Identity"); identity != "" {
+		attrs = append(attrs, originatingIdentity(identity))
+	}
+
+	if recorder.status >= http.StatusBadRequest {
+		var failure struct {
+			Description string `json:"description"`
+		}
+		if json.Unmarshal(recorder.body.Bytes(), &failure) == nil && failure.Description != "" {
+			attrs = append(attrs, slog.String("error", l.redactor.String(failure.Description)))
+		}
+	}
+
+	if debug {
+		attrs = append(attrs,
+			slog.String("request_body", string(l.redactor.JSON(requestBody.Bytes()))),
+			slog.String("response_body", string(l.redactor.JSON(recorder.body.Bytes()))),
+		)
+	}
+
+	l.logger.LogAttrs(req.Context(), level(recorder.status), "broker request", attrs...)
+}
+
+func routeTemplate(req *http.Request) string {
+	if route := mux.CurrentRoute(req); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return req.URL.Path
+}
+
+// originatingIdentity decodes the X-Broker-API-Originating-Identity header,
+// which holds the platform name followed by a base64 encoded JSON value.
+func originatingIdentity(header string) slog.Attr {
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return slog.String("originating_identity", header)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return slog.String("originating_identity", header)
+	}
+
+	return slog.Group("originating_identity",
+		slog.String("platform", parts[0]),
+		slog.String("value", string(value)),
+	)
+}
+
+func level(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,165 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/redact"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logger", func() {
+	Describe("ServeHTTP", func() {
+		var buffer *bytes.Buffer
+		var status int
+		var body string
+		var logger *slog.Logger
+		var router *mux.Router
+		var writer *httptest.ResponseRecorder
+
+		logLine := func() map[string]interface{} {
+			var line map[string]interface{}
+			Expect(json.Unmarshal(buffer.Bytes(), &line)).To(Succeed())
+			return line
+		}
+
+		BeforeEach(func() {
+			buffer = bytes.NewBuffer([]byte{})
+			status = http.StatusTeapot
+			body = ""
+			logger = slog.New(slog.NewJSONHandler(buffer, nil))
+			writer = httptest.NewRecorder()
+		})
+
+		JustBeforeEach(func() {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Body != nil {
+					_, err := ioutil.ReadAll(req.Body)
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				w.WriteHeader(status)
+				w.Write([]byte(body))
+			})
+
+			router = mux.NewRouter()
+			router.Handle("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", middleware.NewLogger(handler, logger, redact.New()))
+		})
+
+		It("logs the request with its route, IDs and status", func() {
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id/service_bindings/binding-id", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.Header.Set("X-Broker-API-Version", "2.13")
+			request.Header.Set("X-Broker-API-Originating-Identity", "cloudfoundry eyJ1c2VyX2lkIjoiYWRtaW4ifQ==")
+
+			router.ServeHTTP(writer, request.WithContext(requestid.NewContext(request.Context(), "request-id")))
+
+			Expect(writer.Code).To(Equal(http.StatusTeapot))
+
+			line := logLine()
+			Expect(line).To(HaveKeyWithValue("level", "WARN"))
+			Expect(line).To(HaveKeyWithValue("msg", "broker request"))
+			Expect(line).To(HaveKeyWithValue("method", "PUT"))
+			Expect(line).To(HaveKeyWithValue("route", "/v2/service_instances/{instance_id}/service_bindings/{binding_id}"))
+			Expect(line).To(HaveKeyWithValue("request_id", "request-id"))
+			Expect(line).To(HaveKeyWithValue("instance_id", "instance-id"))
+			Expect(line).To(HaveKeyWithValue("binding_id", "binding-id"))
+			Expect(line).To(HaveKeyWithValue("status", float64(http.StatusTeapot)))
+			Expect(line).To(HaveKey("latency"))
+			Expect(line).To(HaveKeyWithValue("api_version", "2.13"))
+			Expect(line).To(HaveKeyWithValue("originating_identity", map[string]interface{}{
+				"platform": "cloudfoundry",
+				"value":    `{"user_id":"admin"}`,
+			}))
+		})
+
+		It("logs successful requests at the info level", func() {
+			status = http.StatusOK
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id/service_bindings/binding-id", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(writer, request)
+
+			Expect(logLine()).To(HaveKeyWithValue("level", "INFO"))
+			Expect(logLine()).NotTo(HaveKey("originating_identity"))
+		})
+
+		It("logs failed requests at the error level", func() {
+			status = http.StatusInternalServerError
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id/service_bindings/binding-id", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(writer, request)
+
+			Expect(logLine()).To(HaveKeyWithValue("level", "ERROR"))
+		})
+
+		It("logs the redacted error description of failed requests", func() {
+			status = http.StatusInternalServerError
+			body = `{"description": "dial postgres://admin:hunter2@db failed"}`
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id/service_bindings/binding-id", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(writer, request)
+
+			Expect(writer.Body.String()).To(ContainSubstring("hunter2"))
+			Expect(logLine()).To(HaveKeyWithValue("error", "dial postgres://admin:[REDACTED]@db failed"))
+			Expect(buffer.String()).NotTo(ContainSubstring("hunter2"))
+		})
+
+		It("does not log bodies unless debug logging is enabled", func() {
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id/service_bindings/binding-id", bytes.NewBufferString(`{"service_id": "service-id"}`))
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(writer, request)
+
+			Expect(logLine()).NotTo(HaveKey("request_body"))
+			Expect(logLine()).NotTo(HaveKey("response_body"))
+		})
+
+		Context("when debug logging is enabled", func() {
+			BeforeEach(func() {
+				logger = slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			})
+
+			It("logs the redacted request and response bodies", func() {
+				status = http.StatusCreated
+				body = `{"credentials": {"password": "hunter2"}}`
+				request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id/service_bindings/binding-id", bytes.NewBufferString(`{
+					"service_id": "service-id",
+					"parameters": {"admin_password": "shh"}
+				}`))
+				if err != nil {
+					panic(err)
+				}
+
+				router.ServeHTTP(writer, request)
+
+				line := logLine()
+				Expect(line["request_body"]).To(MatchJSON(`{"service_id": "service-id", "parameters": "[REDACTED]"}`))
+				Expect(line["response_body"]).To(MatchJSON(`{"credentials": "[REDACTED]"}`))
+				Expect(buffer.String()).NotTo(ContainSubstring("hunter2"))
+				Expect(buffer.String()).NotTo(ContainSubstring("shh"))
+			})
+		})
+	})
+})
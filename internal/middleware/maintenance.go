@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+type maintenanceStatus interface {
+	Status() (enabled bool, description string, retryAfter time.Duration)
+}
+
+// Maintenance answers mutating requests with a 503 Service Unavailable
+// response while maintenance mode is enabled. Safe requests, such as those
+// for the catalog, are always served.
+type Maintenance struct {
+	Handler http.Handler
+	status  maintenanceStatus
+}
+
+func NewMaintenance(handler http.Handler, status maintenanceStatus) http.Handler {
+	return Maintenance{
+		Handler: handler,
+		status:  status,
+	}
+}
+
+func (m Maintenance) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == "GET" || req.Method == "HEAD" {
+		m.Handler.ServeHTTP(w, req)
+		return
+	}
+
+	enabled, description, delay := m.status.Status()
+	if !enabled {
+		m.Handler.ServeHTTP(w, req)
+		return
+	}
+
+	if delay > 0 {
+		retryAfter(w, delay)
+	}
+	fail(w, req, http.StatusServiceUnavailable, description)
+}
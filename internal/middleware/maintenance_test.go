@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeMaintenanceStatus struct {
+	enabled     bool
+	description string
+	retryAfter  time.Duration
+}
+
+func (s *fakeMaintenanceStatus) Status() (bool, string, time.Duration) {
+	return s.enabled, s.description, s.retryAfter
+}
+
+var _ = Describe("Maintenance", func() {
+	var status *fakeMaintenanceStatus
+	var maintenance http.Handler
+
+	BeforeEach(func() {
+		status = &fakeMaintenanceStatus{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		maintenance = middleware.NewMaintenance(handler, status)
+	})
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest(method, "/foo", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		maintenance.ServeHTTP(writer, request)
+		return writer
+	}
+
+	It("serves requests while maintenance mode is disabled", func() {
+		Expect(serve("PUT").Code).To(Equal(http.StatusCreated))
+	})
+
+	It("refuses mutating requests while maintenance mode is enabled, but still serves safe requests", func() {
+		status.enabled = true
+		status.description = "upgrading the database"
+		status.retryAfter = 90 * time.Second
+
+		writer := serve("PUT")
+		Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(writer.Header().Get("Retry-After")).To(Equal("90"))
+		Expect(writer.Body.String()).To(MatchJSON(`{"description":"upgrading the database"}`))
+
+		Expect(serve("GET").Code).To(Equal(http.StatusCreated))
+	})
+
+	It("omits the Retry-After header without a delay", func() {
+		status.enabled = true
+
+		writer := serve("DELETE")
+		Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(writer.Header()).NotTo(HaveKey("Retry-After"))
+	})
+})
@@ -0,0 +1,29 @@
+package middleware
+
+import "net/http"
+
+type requestRecorder interface {
+	RequestServed(operation string, status int)
+}
+
+type Metrics struct {
+	Handler   http.Handler
+	recorder  requestRecorder
+	operation string
+}
+
+func NewMetrics(handler http.Handler, recorder requestRecorder, operation string) http.Handler {
+	return Metrics{
+		Handler:   handler,
+		recorder:  recorder,
+		operation: operation,
+	}
+}
+
+func (m Metrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	recorder := newStatusRecorder(w)
+
+	m.Handler.ServeHTTP(recorder, req)
+
+	m.recorder.RequestServed(m.operation, recorder.status)
+}
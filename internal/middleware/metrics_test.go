@@ -0,0 +1,43 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type RequestRecorder struct {
+	Operation string
+	Status    int
+}
+
+func (r *RequestRecorder) RequestServed(operation string, status int) {
+	r.Operation = operation
+	r.Status = status
+}
+
+var _ = Describe("Metrics", func() {
+	Describe("ServeHTTP", func() {
+		It("records the operation and status code of the request", func() {
+			recorder := &RequestRecorder{}
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+			writer := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", "/foo", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			middleware.NewMetrics(handler, recorder, "catalog").ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusTeapot))
+			Expect(recorder.Operation).To(Equal("catalog"))
+			Expect(recorder.Status).To(Equal(http.StatusTeapot))
+		})
+	})
+})
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	verificationTTL   = 5 * time.Minute
+	verificationLimit = 1024
+)
+
+// Bounds of the parameters of argon2id hashes, which would otherwise let
+// a hash take down the broker: argon2 panics without iterations or
+// threads, and allocates the given memory, in KiB, on every verification.
+const (
+	maxArgon2Memory     = 1 << 20
+	maxArgon2Iterations = 64
+	maxArgon2KeyLength  = 1024
+)
+
+// verifyPassword reports whether password matches hash, a bcrypt hash or
+// an argon2id hash in the PHC string format, such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>. Hashes in any other
+// format, or rejected by CheckPasswordHash, never match.
+func verifyPassword(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, err := parseArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		derived := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+		return subtle.ConstantTimeCompare(derived, params.key) == 1
+	default:
+		return false
+	}
+}
+
+// CheckPasswordHash returns an error unless hash is a bcrypt hash, or an
+// argon2id hash whose parameters are within bounds.
+func CheckPasswordHash(hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		_, err := bcrypt.Cost([]byte(hash))
+		return err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		_, err := parseArgon2id(hash)
+		return err
+	default:
+		return errors.New("password hash is neither a bcrypt nor an argon2id hash")
+	}
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func parseArgon2id(hash string) (argon2idParams, error) {
+	var params argon2idParams
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, errors.New("argon2id hash is not in the PHC string format")
+	}
+	if parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return params, fmt.Errorf("argon2id hash has unsupported version %q", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, fmt.Errorf("argon2id hash has malformed parameters %q", parts[3])
+	}
+	if params.iterations == 0 || params.iterations > maxArgon2Iterations {
+		return params, fmt.Errorf("argon2id hash has %d iterations, outside of 1 to %d", params.iterations, maxArgon2Iterations)
+	}
+	if params.parallelism == 0 {
+		return params, errors.New("argon2id hash has a parallelism of 0")
+	}
+	if params.memory < 8*uint32(params.parallelism) || params.memory > maxArgon2Memory {
+		return params, fmt.Errorf("argon2id hash uses %d KiB of memory, outside of %d to %d", params.memory, 8*uint32(params.parallelism), maxArgon2Memory)
+	}
+
+	var err error
+	params.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, errors.New("argon2id hash has a malformed salt")
+	}
+
+	params.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(params.key) == 0 || len(params.key) > maxArgon2KeyLength {
+		return params, errors.New("argon2id hash has a malformed key")
+	}
+
+	return params, nil
+}
+
+// VerificationCache remembers recent successful password verifications,
+// so that the cost of hashing is only paid once in a while for each set of
+// credentials. Only digests of the credentials are kept in memory. It is
+// safe for concurrent use, and meant to be shared by the authenticators of
+// every route.
+type VerificationCache struct {
+	mutex   sync.Mutex
+	expires map[[sha256.Size]byte]time.Time
+	now     func() time.Time
+	hashes  func(hash, password string) bool
+}
+
+func NewVerificationCache() *VerificationCache {
+	return &VerificationCache{
+		expires: map[[sha256.Size]byte]time.Time{},
+		now:     time.Now,
+		hashes:  verifyPassword,
+	}
+}
+
+// verify reports whether password matches hash, consulting the cache
+// first.
+func (c *VerificationCache) verify(hash, username, password string) bool {
+	key := digest(hash, username, password)
+	now := c.now()
+
+	c.mutex.Lock()
+	expires, ok := c.expires[key]
+	c.mutex.Unlock()
+	if ok && now.Before(expires) {
+		return true
+	}
+
+	if !c.hashes(hash, password) {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.expires) >= verificationLimit {
+		for key, expires := range c.expires {
+			if !now.Before(expires) {
+				delete(c.expires, key)
+			}
+		}
+		if len(c.expires) >= verificationLimit {
+			c.expires = map[[sha256.Size]byte]time.Time{}
+		}
+	}
+	c.expires[key] = now.Add(verificationTTL)
+
+	return true
+}
+
+// digest hashes the given values so that they can be compared in constant
+// time, whatever their length. Each value is length-prefixed so that the
+// boundaries between values are unambiguous.
+func digest(values ...string) [sha256.Size]byte {
+	hash := sha256.New()
+	for _, value := range values {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(value)))
+		hash.Write(length[:])
+		hash.Write([]byte(value))
+	}
+
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+	return key
+}
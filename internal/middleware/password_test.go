@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type hashedCredentialer []domain.Credentials
+
+func (c hashedCredentialer) Credentials() (string, string) {
+	return "", ""
+}
+
+func (c hashedCredentialer) AllCredentials() []domain.Credentials {
+	return c
+}
+
+func argon2idHash(password string) string {
+	salt := []byte("some-salt-value!")
+	key := argon2.IDKey([]byte(password), salt, 1, 1024, 1, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=1024,t=1,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+var _ = Describe("verifyPassword", func() {
+	It("verifies bcrypt hashes", func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(verifyPassword(string(hash), "password")).To(BeTrue())
+		Expect(verifyPassword(string(hash), "passwort")).To(BeFalse())
+	})
+
+	It("verifies argon2id hashes", func() {
+		hash := argon2idHash("password")
+
+		Expect(verifyPassword(hash, "password")).To(BeTrue())
+		Expect(verifyPassword(hash, "passwort")).To(BeFalse())
+	})
+
+	It("rejects malformed or unknown hashes", func() {
+		Expect(verifyPassword("password", "password")).To(BeFalse())
+		Expect(verifyPassword("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", "password")).To(BeFalse())
+		Expect(verifyPassword("$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5", "password")).To(BeFalse())
+	})
+
+	It("rejects argon2id hashes with parameters out of bounds", func() {
+		for _, params := range []string{"m=1024,t=0,p=1", "m=1024,t=1,p=0", "m=4294967295,t=1,p=1", "m=1024,t=1000,p=1", "m=4,t=1,p=1", "m=1024,t=1"} {
+			hash := strings.Replace(argon2idHash("password"), "m=1024,t=1,p=1", params, 1)
+
+			Expect(verifyPassword(hash, "password")).To(BeFalse(), params)
+			Expect(CheckPasswordHash(hash)).To(HaveOccurred(), params)
+		}
+	})
+})
+
+var _ = Describe("CheckPasswordHash", func() {
+	It("accepts bcrypt and argon2id hashes", func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(CheckPasswordHash(string(hash))).To(Succeed())
+		Expect(CheckPasswordHash(argon2idHash("password"))).To(Succeed())
+	})
+
+	It("rejects malformed or unknown hashes", func() {
+		Expect(CheckPasswordHash("password")).To(HaveOccurred())
+		Expect(CheckPasswordHash("$2a$malformed")).To(HaveOccurred())
+		Expect(CheckPasswordHash("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA")).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("VerificationCache", func() {
+	var cache *VerificationCache
+	var now time.Time
+	var verifications int
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		verifications = 0
+
+		cache = NewVerificationCache()
+		cache.now = func() time.Time { return now }
+		cache.hashes = func(hash, password string) bool {
+			verifications++
+			return hash == "hash-of-"+password
+		}
+	})
+
+	It("remembers successful verifications for a while", func() {
+		Expect(cache.verify("hash-of-password", "username", "password")).To(BeTrue())
+		Expect(cache.verify("hash-of-password", "username", "password")).To(BeTrue())
+		Expect(verifications).To(Equal(1))
+
+		now = now.Add(verificationTTL)
+		Expect(cache.verify("hash-of-password", "username", "password")).To(BeTrue())
+		Expect(verifications).To(Equal(2))
+	})
+
+	It("does not remember failed verifications", func() {
+		Expect(cache.verify("hash-of-password", "username", "passwort")).To(BeFalse())
+		Expect(cache.verify("hash-of-password", "username", "passwort")).To(BeFalse())
+		Expect(verifications).To(Equal(2))
+	})
+
+	It("verifies again when the hash changes", func() {
+		Expect(cache.verify("hash-of-password", "username", "password")).To(BeTrue())
+		Expect(cache.verify("other-hash-of-password", "username", "password")).To(BeFalse())
+		Expect(verifications).To(Equal(2))
+	})
+
+	It("is shared by the authenticators built with it", func() {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+		credentialer := hashedCredentialer{{Username: "username", PasswordHash: "hash-of-password"}}
+
+		for _, route := range []string{"/v2/catalog", "/v2/service_instances/instance-id"} {
+			writer := httptest.NewRecorder()
+			request := httptest.NewRequest("GET", route, nil)
+			request.SetBasicAuth("username", "password")
+
+			NewAuthenticator(handler, credentialer, cache).ServeHTTP(writer, request)
+			Expect(writer.Code).To(Equal(http.StatusOK))
+		}
+		Expect(verifications).To(Equal(1))
+	})
+
+	It("is bounded in size", func() {
+		for i := 0; i <= verificationLimit; i++ {
+			password := fmt.Sprintf("password-%d", i)
+			Expect(cache.verify("hash-of-"+password, "username", password)).To(BeTrue())
+		}
+
+		Expect(len(cache.expires)).To(BeNumerically("<=", verificationLimit))
+	})
+})
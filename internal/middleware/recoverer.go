@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+)
+
+type PanicHook func(req *http.Request, recovered interface{}, stack []byte)
+
+type Recoverer struct {
+	Handler http.Handler
+	logger  *slog.Logger
+	hook    PanicHook
+}
+
+func NewRecoverer(handler http.Handler, logger *slog.Logger, hook PanicHook) http.Handler {
+	return Recoverer{
+		Handler: handler,
+		logger:  logger,
+		hook:    hook,
+	}
+}
+
+func (r Recoverer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	recorder := newStatusRecorder(w)
+
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+
+		stack := debug.Stack()
+		id := requestid.FromContext(req.Context())
+		r.logger.LogAttrs(req.Context(), slog.LevelError, "panic serving broker request",
+			slog.String("method", req.Method),
+			slog.String("route", routeTemplate(req)),
+			slog.String("request_id", id),
+			slog.String("panic", fmt.Sprint(recovered)),
+			slog.String("stack", string(stack)),
+		)
+
+		if r.hook != nil {
+			r.hook(req, recovered, stack)
+		}
+
+		if recorder.wroteHeader {
+			return
+		}
+
+		fail(w, req, http.StatusInternalServerError, "an unexpected error occurred")
+	}()
+
+	r.Handler.ServeHTTP(recorder, req)
+}
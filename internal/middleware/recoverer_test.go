@@ -0,0 +1,122 @@
+package middleware_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recoverer", func() {
+	Describe("ServeHTTP", func() {
+		var buffer *bytes.Buffer
+		var logger *slog.Logger
+		var hookCalledWith interface{}
+		var hookStack []byte
+		var hook middleware.PanicHook
+		var writer *httptest.ResponseRecorder
+		var request *http.Request
+
+		BeforeEach(func() {
+			var err error
+			buffer = bytes.NewBuffer([]byte{})
+			logger = slog.New(slog.NewJSONHandler(buffer, nil))
+			hookCalledWith = nil
+			hook = func(req *http.Request, recovered interface{}, stack []byte) {
+				hookCalledWith = recovered
+				hookStack = stack
+			}
+			writer = httptest.NewRecorder()
+			request, err = http.NewRequest("GET", "/foo", nil)
+			if err != nil {
+				panic(err)
+			}
+			request = request.WithContext(requestid.NewContext(request.Context(), "request-id"))
+		})
+
+		It("delegates to the handler", func() {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			middleware.NewRecoverer(handler, logger, hook).ServeHTTP(writer, request)
+
+			Expect(writer.Code).To(Equal(http.StatusTeapot))
+			Expect(hookCalledWith).To(BeNil())
+			Expect(buffer.Len()).To(BeZero())
+		})
+
+		Context("when the handler panics", func() {
+			var handler http.Handler
+
+			BeforeEach(func() {
+				handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					panic("BANG!")
+				})
+			})
+
+			It("returns a 500 with a description of the failure", func() {
+				middleware.NewRecoverer(handler, logger, hook).ServeHTTP(writer, request)
+
+				Expect(writer.Code).To(Equal(http.StatusInternalServerError))
+				Expect(writer.Header().Get("Content-Type")).To(Equal("application/json"))
+				Expect(writer.Body.String()).To(MatchJSON(`{"description": "an unexpected error occurred (request ID: request-id)"}`))
+			})
+
+			It("logs the stack trace", func() {
+				middleware.NewRecoverer(handler, logger, hook).ServeHTTP(writer, request)
+
+				Expect(buffer.String()).To(ContainSubstring(`"level":"ERROR"`))
+				Expect(buffer.String()).To(ContainSubstring(`"panic":"BANG!"`))
+				Expect(buffer.String()).To(ContainSubstring(`"request_id":"request-id"`))
+				Expect(buffer.String()).To(ContainSubstring(`recoverer_test.go`))
+			})
+
+			It("reports the panic to the hook", func() {
+				middleware.NewRecoverer(handler, logger, hook).ServeHTTP(writer, request)
+
+				Expect(hookCalledWith).To(Equal("BANG!"))
+				Expect(string(hookStack)).To(ContainSubstring("recoverer_test.go"))
+			})
+
+			It("does not require a hook", func() {
+				middleware.NewRecoverer(handler, logger, nil).ServeHTTP(writer, request)
+
+				Expect(writer.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("when the handler panics after writing a response", func() {
+			It("leaves the response alone", func() {
+				handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusCreated)
+					panic("BANG!")
+				})
+
+				middleware.NewRecoverer(handler, logger, hook).ServeHTTP(writer, request)
+
+				Expect(writer.Code).To(Equal(http.StatusCreated))
+				Expect(writer.Body.String()).To(BeEmpty())
+				Expect(hookCalledWith).To(Equal("BANG!"))
+			})
+		})
+
+		Context("when the handler aborts", func() {
+			It("lets the server abort the response", func() {
+				handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					panic(http.ErrAbortHandler)
+				})
+
+				Expect(func() {
+					middleware.NewRecoverer(handler, logger, hook).ServeHTTP(writer, request)
+				}).To(PanicWith(http.ErrAbortHandler))
+			})
+		})
+	})
+})
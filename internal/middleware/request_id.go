@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+)
+
+// RequestIDHeaders are the headers a request ID is read from, in order of
+// preference, and echoed in.
+var RequestIDHeaders = []string{"X-Broker-API-Request-Identity", "X-Request-Id"}
+
+type RequestID struct {
+	Handler http.Handler
+}
+
+func NewRequestID(handler http.Handler) http.Handler {
+	return RequestID{
+		Handler: handler,
+	}
+}
+
+func (r RequestID) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var id string
+	for _, header := range RequestIDHeaders {
+		if value := req.Header.Get(header); requestid.Valid(value) {
+			id = value
+			break
+		}
+	}
+
+	if id == "" {
+		id = requestid.Generate()
+	}
+
+	for _, header := range RequestIDHeaders {
+		w.Header().Set(header, id)
+	}
+
+	r.Handler.ServeHTTP(w, req.WithContext(requestid.NewContext(req.Context(), id)))
+}
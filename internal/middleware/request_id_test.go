@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RequestID", func() {
+	Describe("ServeHTTP", func() {
+		var handlerID string
+		var handler http.Handler
+		var writer *httptest.ResponseRecorder
+		var request *http.Request
+
+		BeforeEach(func() {
+			var err error
+			handlerID = ""
+			handler = middleware.NewRequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				handlerID = requestid.FromContext(req.Context())
+			}))
+			writer = httptest.NewRecorder()
+			request, err = http.NewRequest("GET", "/foo", nil)
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		It("uses the request identity given by the platform", func() {
+			request.Header.Set("X-Broker-API-Request-Identity", "platform-id")
+			request.Header.Set("X-Request-Id", "proxy-id")
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(handlerID).To(Equal("platform-id"))
+			Expect(writer.Header().Get("X-Broker-API-Request-Identity")).To(Equal("platform-id"))
+			Expect(writer.Header().Get("X-Request-Id")).To(Equal("platform-id"))
+		})
+
+		It("falls back to the X-Request-Id header", func() {
+			request.Header.Set("X-Request-Id", "proxy-id")
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(handlerID).To(Equal("proxy-id"))
+			Expect(writer.Header().Get("X-Broker-API-Request-Identity")).To(Equal("proxy-id"))
+		})
+
+		It("generates an ID when none, or an invalid one, is given", func() {
+			request.Header.Set("X-Request-Id", "forged\nlog line")
+
+			handler.ServeHTTP(writer, request)
+
+			Expect(handlerID).To(HaveLen(36))
+			Expect(writer.Header().Get("X-Request-Id")).To(Equal(handlerID))
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// maxRecordedBody bounds the size of the bodies kept for logging.
+const maxRecordedBody = 64 * 1024
+
+// statusRecorder wraps an http.ResponseWriter, recording the status code
+// and the start of the body written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        limitedBuffer
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(body []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(body)
+	return r.ResponseWriter.Write(body)
+}
+
+// limitedBuffer is a bytes.Buffer that silently discards everything
+// written past maxRecordedBody.
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := maxRecordedBody - b.Len(); remaining < len(p) {
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
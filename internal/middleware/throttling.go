@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/throttle"
+)
+
+// ClientKey identifies the client making a request, for rate limiting
+// and lockouts.
+type ClientKey func(req *http.Request) string
+
+// RemoteClient identifies the client making a request by the IP address
+// of the connection.
+func RemoteClient(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// ForwardedClient returns a ClientKey identifying the client making a
+// request by the last address of the X-Forwarded-For header that is not
+// one of the trusted proxies. Requests whose connection does not come
+// from a trusted proxy are identified by the address of the connection,
+// so that clients cannot pick their own identity.
+func ForwardedClient(trustedProxies []*net.IPNet) ClientKey {
+	trusted := func(address string) bool {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return false
+		}
+		for _, proxy := range trustedProxies {
+			if proxy.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(req *http.Request) string {
+		client := RemoteClient(req)
+		if !trusted(client) {
+			return client
+		}
+
+		forwarded := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			address := strings.TrimSpace(forwarded[i])
+			if net.ParseIP(address) == nil {
+				break
+			}
+
+			client = address
+			if !trusted(address) {
+				break
+			}
+		}
+
+		return client
+	}
+}
+
+// retryAfter sets the Retry-After header to the given delay, rounded up
+// to the second.
+func retryAfter(w http.ResponseWriter, delay time.Duration) {
+	seconds := int(math.Ceil(delay.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// RateLimiter answers requests from clients that have exhausted their
+// token bucket with a 429 Too Many Requests response. Clients are
+// identified by the given ClientKey, or by RemoteClient if it is nil.
+type RateLimiter struct {
+	Handler http.Handler
+	limiter *throttle.Limiter
+	client  ClientKey
+}
+
+func NewRateLimiter(handler http.Handler, limiter *throttle.Limiter, client ClientKey) http.Handler {
+	if client == nil {
+		client = RemoteClient
+	}
+
+	return RateLimiter{
+		Handler: handler,
+		limiter: limiter,
+		client:  client,
+	}
+}
+
+func (r RateLimiter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if ok, delay := r.limiter.Allow(r.client(req)); !ok {
+		retryAfter(w, delay)
+		fail(w, req, http.StatusTooManyRequests, "too many requests")
+		return
+	}
+
+	r.Handler.ServeHTTP(w, req)
+}
+
+// Lockout answers requests from clients that have failed to authenticate
+// too many times in a row with a 429 Too Many Requests response, without
+// checking their credentials. It must wrap the authenticator, whose 401
+// responses it counts as failures, and the authenticator must wrap an
+// Authenticated handler: only requests reaching it reset the failures of
+// the client, so that responses given before the credentials are checked,
+// such as a 403 for a client certificate, do not. Clients are identified
+// by the given ClientKey, or by RemoteClient if it is nil.
+type Lockout struct {
+	Handler http.Handler
+	lockout *throttle.Lockout
+	client  ClientKey
+}
+
+func NewLockout(handler http.Handler, lockout *throttle.Lockout, client ClientKey) http.Handler {
+	if client == nil {
+		client = RemoteClient
+	}
+
+	return Lockout{
+		Handler: handler,
+		lockout: lockout,
+		client:  client,
+	}
+}
+
+func (l Lockout) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	client := l.client(req)
+	if locked, delay := l.lockout.Locked(client); locked {
+		retryAfter(w, delay)
+		fail(w, req, http.StatusTooManyRequests, "too many failed authentication attempts")
+		return
+	}
+
+	authenticated := new(bool)
+	req = req.WithContext(context.WithValue(req.Context(), authenticatedKey{}, authenticated))
+
+	recorder := newStatusRecorder(w)
+	l.Handler.ServeHTTP(recorder, req)
+
+	if *authenticated {
+		l.lockout.Succeeded(client)
+	} else if recorder.status == http.StatusUnauthorized {
+		l.lockout.Failed(client)
+	}
+}
+
+type authenticatedKey struct{}
+
+// Authenticated marks the requests it serves as authenticated, for the
+// Lockout wrapping the authenticator that wraps it.
+type Authenticated struct {
+	Handler http.Handler
+}
+
+func NewAuthenticated(handler http.Handler) http.Handler {
+	return Authenticated{
+		Handler: handler,
+	}
+}
+
+func (a Authenticated) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if authenticated, ok := req.Context().Value(authenticatedKey{}).(*bool); ok {
+		*authenticated = true
+	}
+
+	a.Handler.ServeHTTP(w, req)
+}
+
+// ConcurrencyLimiter answers requests with a 503 Service Unavailable
+// response while the given number of requests are already being served.
+type ConcurrencyLimiter struct {
+	Handler http.Handler
+	slots   chan struct{}
+}
+
+func NewConcurrencyLimiter(handler http.Handler, limit int) http.Handler {
+	return ConcurrencyLimiter{
+		Handler: handler,
+		slots:   make(chan struct{}, limit),
+	}
+}
+
+func (c ConcurrencyLimiter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	default:
+		retryAfter(w, time.Second)
+		fail(w, req, http.StatusServiceUnavailable, "too many concurrent requests for this operation")
+		return
+	}
+
+	c.Handler.ServeHTTP(w, req)
+}
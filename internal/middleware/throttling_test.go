@@ -0,0 +1,195 @@
+package middleware_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"github.com/pivotal-cf-experimental/envoy/internal/throttle"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Throttling", func() {
+	var status int
+	var calls int
+	var handler http.Handler
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		calls = 0
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			w.WriteHeader(status)
+		})
+	})
+
+	serve := func(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest("GET", "/foo", nil)
+		if err != nil {
+			panic(err)
+		}
+		request.RemoteAddr = remoteAddr
+
+		h.ServeHTTP(writer, request)
+		return writer
+	}
+
+	Describe("RateLimiter", func() {
+		It("returns a 429 with a Retry-After header once the client exhausted its bucket", func() {
+			limiter := middleware.NewRateLimiter(handler, throttle.NewLimiter(0.5, 1), nil)
+
+			Expect(serve(limiter, "10.0.0.1:1234").Code).To(Equal(http.StatusOK))
+
+			writer := serve(limiter, "10.0.0.1:5678")
+			Expect(writer.Code).To(Equal(http.StatusTooManyRequests))
+			Expect(writer.Header().Get("Retry-After")).To(Equal("2"))
+			Expect(writer.Body.String()).To(MatchJSON(`{"description":"too many requests"}`))
+			Expect(calls).To(Equal(1))
+
+			Expect(serve(limiter, "10.0.0.2:1234").Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("Lockout", func() {
+		It("locks clients out after repeated authentication failures", func() {
+			lockout := middleware.NewLockout(handler, throttle.NewLockout(2, time.Minute), nil)
+
+			status = http.StatusUnauthorized
+			serve(lockout, "10.0.0.1:1234")
+			serve(lockout, "10.0.0.1:1234")
+
+			status = http.StatusOK
+			writer := serve(lockout, "10.0.0.1:1234")
+			Expect(writer.Code).To(Equal(http.StatusTooManyRequests))
+			Expect(writer.Header().Get("Retry-After")).To(Equal("60"))
+			Expect(calls).To(Equal(2))
+
+			Expect(serve(lockout, "10.0.0.2:1234").Code).To(Equal(http.StatusOK))
+		})
+
+		It("only resets the failures of clients whose credentials were accepted", func() {
+			authenticated := false
+			authenticator := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if !authenticated {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				middleware.NewAuthenticated(handler).ServeHTTP(w, req)
+			})
+			lockout := middleware.NewLockout(authenticator, throttle.NewLockout(2, time.Minute), nil)
+
+			serve(lockout, "10.0.0.1:1234")
+			authenticated = true
+			serve(lockout, "10.0.0.1:1234")
+			authenticated = false
+			serve(lockout, "10.0.0.1:1234")
+
+			Expect(serve(lockout, "10.0.0.1:1234").Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("does not count other failures", func() {
+			lockout := middleware.NewLockout(handler, throttle.NewLockout(1, time.Minute), nil)
+
+			status = http.StatusForbidden
+			serve(lockout, "10.0.0.1:1234")
+
+			Expect(serve(lockout, "10.0.0.1:1234").Code).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Describe("ForwardedClient", func() {
+		var client middleware.ClientKey
+
+		BeforeEach(func() {
+			_, proxies, err := net.ParseCIDR("10.0.0.0/24")
+			Expect(err).NotTo(HaveOccurred())
+			client = middleware.ForwardedClient([]*net.IPNet{proxies})
+		})
+
+		identify := func(remoteAddr string, forwardedFor ...string) string {
+			request, err := http.NewRequest("GET", "/foo", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.RemoteAddr = remoteAddr
+			for _, value := range forwardedFor {
+				request.Header.Add("X-Forwarded-For", value)
+			}
+
+			return client(request)
+		}
+
+		It("identifies clients by the last address forwarded by a trusted proxy", func() {
+			Expect(identify("10.0.0.1:1234", "203.0.113.7")).To(Equal("203.0.113.7"))
+			Expect(identify("10.0.0.1:1234", "198.51.100.1, 203.0.113.7, 10.0.0.2")).To(Equal("203.0.113.7"))
+			Expect(identify("10.0.0.1:1234", "198.51.100.1", "203.0.113.7")).To(Equal("203.0.113.7"))
+		})
+
+		It("ignores the header of requests that do not come from a trusted proxy", func() {
+			Expect(identify("203.0.113.7:1234", "198.51.100.1")).To(Equal("203.0.113.7"))
+		})
+
+		It("identifies clients by the last trusted address when no other is forwarded", func() {
+			Expect(identify("10.0.0.1:1234")).To(Equal("10.0.0.1"))
+			Expect(identify("10.0.0.1:1234", "not-an-address, 10.0.0.2")).To(Equal("10.0.0.2"))
+		})
+
+		It("keeps clients behind the proxy apart in the lockout", func() {
+			lockout := middleware.NewLockout(handler, throttle.NewLockout(1, time.Minute), client)
+			serveForwarded := func(forwardedFor string) *httptest.ResponseRecorder {
+				writer := httptest.NewRecorder()
+				request, err := http.NewRequest("GET", "/foo", nil)
+				if err != nil {
+					panic(err)
+				}
+				request.RemoteAddr = "10.0.0.1:1234"
+				request.Header.Set("X-Forwarded-For", forwardedFor)
+
+				lockout.ServeHTTP(writer, request)
+				return writer
+			}
+
+			status = http.StatusUnauthorized
+			serveForwarded("203.0.113.7")
+
+			status = http.StatusOK
+			Expect(serveForwarded("203.0.113.7").Code).To(Equal(http.StatusTooManyRequests))
+			Expect(serveForwarded("198.51.100.1").Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("ConcurrencyLimiter", func() {
+		It("returns a 503 while the limit is reached", func() {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			blocking := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				close(started)
+				<-release
+			})
+			limiter := middleware.NewConcurrencyLimiter(blocking, 1)
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				serve(limiter, "10.0.0.1:1234")
+				close(done)
+			}()
+			<-started
+
+			writer := serve(limiter, "10.0.0.2:1234")
+			Expect(writer.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(writer.Header().Get("Retry-After")).To(Equal("1"))
+
+			close(release)
+			<-done
+
+			limiter = middleware.NewConcurrencyLimiter(handler, 1)
+			Expect(serve(limiter, "10.0.0.2:1234").Code).To(Equal(http.StatusOK))
+		})
+	})
+})
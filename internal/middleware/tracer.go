@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Tracer struct {
+	Handler    http.Handler
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	operation  string
+}
+
+func NewTracer(handler http.Handler, provider trace.TracerProvider, propagator propagation.TextMapPropagator, operation string) http.Handler {
+	return Tracer{
+		Handler:    handler,
+		tracer:     provider.Tracer(tracing.InstrumentationName),
+		propagator: propagator,
+		operation:  operation,
+	}
+}
+
+func (t Tracer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	route := routeTemplate(req)
+	ctx := t.propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	ctx, span := t.tracer.Start(ctx, req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("http.route", route),
+			attribute.String("envoy.operation", t.operation),
+			attribute.String("envoy.request_id", requestid.FromContext(req.Context())),
+		),
+	)
+	defer span.End()
+
+	recorder := newStatusRecorder(w)
+	t.Handler.ServeHTTP(recorder, req.WithContext(ctx))
+
+	span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+	if recorder.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(recorder.status))
+	}
+}
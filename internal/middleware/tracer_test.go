@@ -0,0 +1,86 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracer", func() {
+	Describe("ServeHTTP", func() {
+		var exporter *tracetest.InMemoryExporter
+		var status int
+		var handlerSpan trace.SpanContext
+		var router *mux.Router
+
+		BeforeEach(func() {
+			exporter = tracetest.NewInMemoryExporter()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			status = http.StatusCreated
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(req.Context())
+				w.WriteHeader(status)
+			})
+
+			router = mux.NewRouter()
+			router.Handle("/v2/service_instances/{instance_id}", middleware.NewTracer(handler, provider, propagation.TraceContext{}, "provision"))
+		})
+
+		It("continues the trace given in the traceparent header", func() {
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id", nil)
+			if err != nil {
+				panic(err)
+			}
+			request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Name).To(Equal("PUT /v2/service_instances/{instance_id}"))
+			Expect(spans[0].SpanKind).To(Equal(trace.SpanKindServer))
+			Expect(spans[0].SpanContext.TraceID().String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+			Expect(spans[0].Parent.SpanID().String()).To(Equal("00f067aa0ba902b7"))
+			Expect(spans[0].Attributes).To(ContainElement(attribute.String("envoy.operation", "provision")))
+			Expect(spans[0].Attributes).To(ContainElement(attribute.Int("http.response.status_code", http.StatusCreated)))
+
+			Expect(handlerSpan).To(Equal(spans[0].SpanContext))
+		})
+
+		It("starts a new trace when no traceparent header is given", func() {
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Parent.IsValid()).To(BeFalse())
+		})
+
+		It("marks the span as failed on server errors", func() {
+			status = http.StatusInternalServerError
+			request, err := http.NewRequest("PUT", "/v2/service_instances/instance-id", nil)
+			if err != nil {
+				panic(err)
+			}
+
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			Expect(exporter.GetSpans()[0].Status.Code).To(Equal(codes.Error))
+		})
+	})
+})
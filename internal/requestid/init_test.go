@@ -0,0 +1,13 @@
+package requestid_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEnvoyRequestIDSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Envoy Request ID Suite")
+}
@@ -0,0 +1,33 @@
+package requestid_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/requestid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("requestid", func() {
+	It("carries the request ID in a context", func() {
+		ctx := requestid.NewContext(context.Background(), "request-id")
+		Expect(requestid.FromContext(ctx)).To(Equal("request-id"))
+		Expect(requestid.FromContext(context.Background())).To(BeEmpty())
+	})
+
+	It("generates random version 4 UUIDs", func() {
+		id := requestid.Generate()
+		Expect(id).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(requestid.Generate()).NotTo(Equal(id))
+	})
+
+	It("only accepts short, printable IDs from clients", func() {
+		Expect(requestid.Valid("0b5f8a36-7c4d-4d0e-9a1e-7b1c9d2e3f40")).To(BeTrue())
+		Expect(requestid.Valid("")).To(BeFalse())
+		Expect(requestid.Valid("forged\nlog line")).To(BeFalse())
+		Expect(requestid.Valid("with space")).To(BeFalse())
+		Expect(requestid.Valid(strings.Repeat("a", 201))).To(BeFalse())
+	})
+})
@@ -0,0 +1,13 @@
+package throttle_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEnvoyThrottleSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Envoy Throttle Suite")
+}
@@ -0,0 +1,77 @@
+package throttle
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleBuckets is the number of buckets above which full buckets are
+// discarded, since a full bucket behaves like a missing one.
+const idleBuckets = 1024
+
+// Limiter keeps a token bucket for every client. Each request takes a
+// token from the bucket of its client; buckets are refilled at a constant
+// rate up to their burst size. It is safe for concurrent use.
+type Limiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewLimiter returns a Limiter refilling buckets with rate tokens per
+// second, holding at most burst tokens.
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the bucket of the client. When the bucket is
+// empty, it returns false, and how long the client should wait before
+// trying again.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if len(l.buckets) > idleBuckets {
+		l.discardFull(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+func (l *Limiter) discardFull(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
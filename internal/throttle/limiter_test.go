@@ -0,0 +1,64 @@
+package throttle
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	var limiter *Limiter
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		limiter = NewLimiter(2, 3)
+		limiter.now = func() time.Time { return now }
+	})
+
+	It("allows bursts up to the burst size", func() {
+		for i := 0; i < 3; i++ {
+			ok, _ := limiter.Allow("client")
+			Expect(ok).To(BeTrue())
+		}
+
+		ok, wait := limiter.Allow("client")
+		Expect(ok).To(BeFalse())
+		Expect(wait).To(Equal(500 * time.Millisecond))
+	})
+
+	It("refills buckets at the given rate", func() {
+		for i := 0; i < 3; i++ {
+			limiter.Allow("client")
+		}
+
+		now = now.Add(500 * time.Millisecond)
+		ok, _ := limiter.Allow("client")
+		Expect(ok).To(BeTrue())
+
+		ok, _ = limiter.Allow("client")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps a bucket per client", func() {
+		for i := 0; i < 3; i++ {
+			limiter.Allow("client")
+		}
+
+		ok, _ := limiter.Allow("another-client")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("discards full buckets once there are many", func() {
+		for i := 0; i <= idleBuckets; i++ {
+			limiter.Allow(fmt.Sprintf("client-%d", i))
+		}
+
+		now = now.Add(time.Minute)
+		limiter.Allow("client")
+
+		Expect(len(limiter.buckets)).To(Equal(1))
+	})
+})
@@ -0,0 +1,93 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Lockout locks clients out for a while once they have failed to
+// authenticate too many times in a row. It is safe for concurrent use.
+type Lockout struct {
+	maxFailures int
+	duration    time.Duration
+	now         func() time.Time
+
+	mutex   sync.Mutex
+	clients map[string]*failures
+}
+
+type failures struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// NewLockout returns a Lockout locking clients out for the given duration
+// after maxFailures consecutive failures.
+func NewLockout(maxFailures int, duration time.Duration) *Lockout {
+	return &Lockout{
+		maxFailures: maxFailures,
+		duration:    duration,
+		now:         time.Now,
+		clients:     map[string]*failures{},
+	}
+}
+
+// Locked reports whether the client is locked out, and for how long.
+func (l *Lockout) Locked(client string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	f, ok := l.clients[client]
+	if !ok {
+		return false, 0
+	}
+
+	remaining := f.lockedUntil.Sub(l.now())
+	if remaining <= 0 {
+		return false, 0
+	}
+
+	return true, remaining
+}
+
+// Failed records a failure of the client to authenticate, locking it out
+// once it has failed too many times in a row.
+func (l *Lockout) Failed(client string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if len(l.clients) > idleBuckets {
+		l.discardUnlocked(now)
+	}
+
+	f, ok := l.clients[client]
+	if !ok {
+		f = &failures{}
+		l.clients[client] = f
+	}
+
+	f.count++
+	if f.count >= l.maxFailures {
+		f.count = 0
+		f.lockedUntil = now.Add(l.duration)
+	}
+}
+
+// discardUnlocked bounds the memory used by the lockout by forgetting the
+// failures of clients that are not locked out.
+func (l *Lockout) discardUnlocked(now time.Time) {
+	for client, f := range l.clients {
+		if !now.Before(f.lockedUntil) {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// Succeeded forgets the failures of the client.
+func (l *Lockout) Succeeded(client string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.clients, client)
+}
@@ -0,0 +1,57 @@
+package throttle
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lockout", func() {
+	var lockout *Lockout
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		lockout = NewLockout(3, time.Minute)
+		lockout.now = func() time.Time { return now }
+	})
+
+	It("locks clients out after too many consecutive failures", func() {
+		lockout.Failed("client")
+		lockout.Failed("client")
+
+		locked, _ := lockout.Locked("client")
+		Expect(locked).To(BeFalse())
+
+		lockout.Failed("client")
+
+		locked, remaining := lockout.Locked("client")
+		Expect(locked).To(BeTrue())
+		Expect(remaining).To(Equal(time.Minute))
+
+		locked, _ = lockout.Locked("another-client")
+		Expect(locked).To(BeFalse())
+	})
+
+	It("lets clients back in after the lockout duration", func() {
+		for i := 0; i < 3; i++ {
+			lockout.Failed("client")
+		}
+
+		now = now.Add(time.Minute)
+
+		locked, _ := lockout.Locked("client")
+		Expect(locked).To(BeFalse())
+	})
+
+	It("forgets failures after a success", func() {
+		lockout.Failed("client")
+		lockout.Failed("client")
+		lockout.Succeeded("client")
+		lockout.Failed("client")
+
+		locked, _ := lockout.Locked("client")
+		Expect(locked).To(BeFalse())
+	})
+})
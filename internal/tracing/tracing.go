@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const InstrumentationName = "github.com/pivotal-cf-experimental/envoy"
+
+// Start starts a span named name as a child of the span held by ctx, using
+// the tracer provider that created that span. When ctx holds no recording
+// span, tracing is disabled for the request: ctx is returned unchanged,
+// along with a span that does nothing.
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.IsRecording() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+
+	return parent.TracerProvider().Tracer(InstrumentationName).Start(ctx, name, options...)
+}
+
+// End records err on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,52 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Start", func() {
+	var exporter *tracetest.InMemoryExporter
+	var provider *sdktrace.TracerProvider
+
+	BeforeEach(func() {
+		exporter = tracetest.NewInMemoryExporter()
+		provider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	})
+
+	It("starts a child of the span in the context", func() {
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+		childContext, child := tracing.Start(ctx, "child")
+		Expect(trace.SpanFromContext(childContext)).To(Equal(child))
+		tracing.End(child, errors.New("BANG!"))
+		parent.End()
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("child"))
+		Expect(spans[0].Parent.SpanID()).To(Equal(parent.SpanContext().SpanID()))
+		Expect(spans[0].Status.Code).To(Equal(codes.Error))
+		Expect(spans[0].Status.Description).To(Equal("BANG!"))
+	})
+
+	It("leaves the context untouched when it holds no recording span", func() {
+		ctx := context.Background()
+
+		childContext, child := tracing.Start(ctx, "child")
+		Expect(childContext).To(Equal(ctx))
+		Expect(child.IsRecording()).To(BeFalse())
+		tracing.End(child, nil)
+
+		Expect(exporter.GetSpans()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,67 @@
+package envoy
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMaintenanceDescription describes the 503 Service Unavailable
+// responses sent while maintenance mode is enabled without a description.
+const DefaultMaintenanceDescription = "the service broker is undergoing maintenance"
+
+// MaintenanceMode is a runtime switch that makes the broker handler refuse
+// provision, bind, unbind and deprovision requests while it is enabled,
+// without restarting the process. The catalog is still served. It is safe
+// for concurrent use.
+type MaintenanceMode struct {
+	mutex       sync.RWMutex
+	enabled     bool
+	description string
+	retryAfter  time.Duration
+}
+
+// NewMaintenanceMode returns a disabled MaintenanceMode.
+func NewMaintenanceMode() *MaintenanceMode {
+	return &MaintenanceMode{}
+}
+
+// Enable refuses mutating requests with a 503 Service Unavailable response
+// holding the given description, DefaultMaintenanceDescription if empty,
+// and a Retry-After header with the given delay, omitted if zero.
+func (m *MaintenanceMode) Enable(description string, retryAfter time.Duration) {
+	if description == "" {
+		description = DefaultMaintenanceDescription
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.enabled = true
+	m.description = description
+	m.retryAfter = retryAfter
+}
+
+// Disable serves mutating requests again.
+func (m *MaintenanceMode) Disable() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.enabled = false
+	m.description = ""
+	m.retryAfter = 0
+}
+
+// Enabled reports whether maintenance mode is enabled.
+func (m *MaintenanceMode) Enabled() bool {
+	enabled, _, _ := m.Status()
+	return enabled
+}
+
+// Status returns whether maintenance mode is enabled, along with the
+// description and Retry-After delay of the responses to refused requests.
+func (m *MaintenanceMode) Status() (enabled bool, description string, retryAfter time.Duration) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.enabled, m.description, m.retryAfter
+}
@@ -0,0 +1,26 @@
+package envoy_test
+
+import (
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MaintenanceMode", func() {
+	It("is disabled until enabled", func() {
+		mode := envoy.NewMaintenanceMode()
+		Expect(mode.Enabled()).To(BeFalse())
+
+		mode.Enable("", time.Minute)
+		enabled, description, retryAfter := mode.Status()
+		Expect(enabled).To(BeTrue())
+		Expect(description).To(Equal(envoy.DefaultMaintenanceDescription))
+		Expect(retryAfter).To(Equal(time.Minute))
+
+		mode.Disable()
+		Expect(mode.Enabled()).To(BeFalse())
+	})
+})
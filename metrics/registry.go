@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of the
+// operation latency histogram.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry records the activity of a service broker and exposes it in the
+// Prometheus text format. It is safe for concurrent use.
+type Registry struct {
+	mutex    sync.Mutex
+	requests *family
+	errors   *family
+	latency  *family
+	inFlight *family
+	reloads  *family
+	changes  *family
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	latency := newFamily("envoy_operation_duration_seconds",
+		"Time taken by the broker to complete an operation.",
+		histogram, "operation", "service_id", "plan_id")
+	latency.buckets = DefaultBuckets
+
+	return &Registry{
+		requests: newFamily("envoy_requests_total",
+			"Number of broker API requests served, by operation and status code.",
+			counter, "operation", "code"),
+		errors: newFamily("envoy_errors_total",
+			"Number of failed broker operations, by operation and error type.",
+			counter, "operation", "error_type"),
+		latency: latency,
+		inFlight: newFamily("envoy_async_operations_in_flight",
+			"Number of asynchronous operations currently in progress.",
+			gauge, "operation"),
+		reloads: newFamily("envoy_catalog_reloads_total",
+			"Number of catalog reloads, by result.",
+			counter, "result"),
+		changes: newFamily("envoy_catalog_changes_total",
+			"Number of services and plans added, removed or changed by catalog reloads.",
+			counter, "kind", "change"),
+	}
+}
+
+// RequestServed records a request to the given operation that was answered
+// with the given status code.
+func (r *Registry) RequestServed(operation string, status int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.requests.add(1, operation, strconv.Itoa(status))
+}
+
+// OperationCompleted records the time taken by the broker to complete an
+// operation for the given service and plan. A non-empty errorType records
+// the operation as failed with an error of that type.
+func (r *Registry) OperationCompleted(operation, serviceID, planID string, duration time.Duration, errorType string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.latency.observe(duration.Seconds(), operation, serviceID, planID)
+	if errorType != "" {
+		r.errors.add(1, operation, errorType)
+	}
+}
+
+// AsyncOperationStarted records the start of an asynchronous operation.
+// The broker handler calls it when it answers a request with a 202
+// Accepted response, and AsyncOperationFinished once the last operation
+// of the service instance is reported as finished.
+func (r *Registry) AsyncOperationStarted(operation string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.inFlight.add(1, operation)
+}
+
+// AsyncOperationFinished records the end of an asynchronous operation.
+func (r *Registry) AsyncOperationFinished(operation string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.inFlight.add(-1, operation)
+}
+
+// CatalogReloaded records an attempt to reload the catalog, which failed
+// if err is not nil.
+func (r *Registry) CatalogReloaded(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	r.reloads.add(1, result)
+}
+
+// CatalogChanged records a service or plan, as given by kind, that was
+// "added", "removed" or "changed" by a catalog reload.
+func (r *Registry) CatalogChanged(kind, change string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.changes.add(1, kind, change)
+}
+
+// ServeHTTP writes every recorded metric in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var buffer bytes.Buffer
+
+	r.mutex.Lock()
+	for _, f := range []*family{r.requests, r.errors, r.latency, r.inFlight, r.reloads, r.changes} {
+		f.writeTo(&buffer)
+	}
+	r.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buffer.Bytes())
+}
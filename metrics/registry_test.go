@@ -0,0 +1,100 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var registry *metrics.Registry
+
+	scrape := func() string {
+		writer := httptest.NewRecorder()
+		request, err := http.NewRequest("GET", "/metrics", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		registry.ServeHTTP(writer, request)
+
+		Expect(writer.Code).To(Equal(http.StatusOK))
+		Expect(writer.Header().Get("Content-Type")).To(Equal("text/plain; version=0.0.4; charset=utf-8"))
+		return writer.Body.String()
+	}
+
+	BeforeEach(func() {
+		registry = metrics.NewRegistry()
+	})
+
+	It("describes every metric, even before anything is recorded", func() {
+		Expect(scrape()).To(Equal(`# HELP envoy_requests_total Number of broker API requests served, by operation and status code.
+# TYPE envoy_requests_total counter
+# HELP envoy_errors_total Number of failed broker operations, by operation and error type.
+# TYPE envoy_errors_total counter
+# HELP envoy_operation_duration_seconds Time taken by the broker to complete an operation.
+# TYPE envoy_operation_duration_seconds histogram
+# HELP envoy_async_operations_in_flight Number of asynchronous operations currently in progress.
+# TYPE envoy_async_operations_in_flight gauge
+# HELP envoy_catalog_reloads_total Number of catalog reloads, by result.
+# TYPE envoy_catalog_reloads_total counter
+# HELP envoy_catalog_changes_total Number of services and plans added, removed or changed by catalog reloads.
+# TYPE envoy_catalog_changes_total counter
+`))
+	})
+
+	It("counts requests by operation and status code", func() {
+		registry.RequestServed("provision", http.StatusCreated)
+		registry.RequestServed("provision", http.StatusCreated)
+		registry.RequestServed("provision", http.StatusConflict)
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`envoy_requests_total{operation="provision",code="201"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_requests_total{operation="provision",code="409"} 1` + "\n"))
+	})
+
+	It("records operation latencies and errors", func() {
+		registry.OperationCompleted("bind", "service-id", "plan-id", 20*time.Millisecond, "")
+		registry.OperationCompleted("bind", "service-id", "plan-id", 3*time.Second, "InternalError")
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`envoy_operation_duration_seconds_bucket{operation="bind",service_id="service-id",plan_id="plan-id",le="0.01"} 0` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_operation_duration_seconds_bucket{operation="bind",service_id="service-id",plan_id="plan-id",le="0.025"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_operation_duration_seconds_bucket{operation="bind",service_id="service-id",plan_id="plan-id",le="5"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_operation_duration_seconds_bucket{operation="bind",service_id="service-id",plan_id="plan-id",le="+Inf"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_operation_duration_seconds_sum{operation="bind",service_id="service-id",plan_id="plan-id"} 3.02` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_operation_duration_seconds_count{operation="bind",service_id="service-id",plan_id="plan-id"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_errors_total{operation="bind",error_type="InternalError"} 1` + "\n"))
+	})
+
+	It("tracks asynchronous operations in flight", func() {
+		registry.AsyncOperationStarted("provision")
+		registry.AsyncOperationStarted("provision")
+		registry.AsyncOperationFinished("provision")
+
+		Expect(scrape()).To(ContainSubstring(`envoy_async_operations_in_flight{operation="provision"} 1` + "\n"))
+	})
+
+	It("counts catalog reloads and changes", func() {
+		registry.CatalogReloaded(nil)
+		registry.CatalogReloaded(errors.New("invalid catalog"))
+		registry.CatalogChanged("plan", "added")
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`envoy_catalog_reloads_total{result="success"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_catalog_reloads_total{result="failure"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`envoy_catalog_changes_total{kind="plan",change="added"} 1` + "\n"))
+	})
+
+	It("escapes label values", func() {
+		registry.OperationCompleted("bind", "service \"quoted\"\n", `plan\id`, time.Millisecond, "")
+
+		Expect(scrape()).To(ContainSubstring(`envoy_operation_duration_seconds_count{operation="bind",service_id="service \"quoted\"\n",plan_id="plan\\id"} 1` + "\n"))
+	})
+})
@@ -7,7 +7,9 @@ import "github.com/pivotal-cf-experimental/envoy/domain"
 // broker that may not apply to some implementations. For example,
 // it could be used to provide a valid Binder and Unbinder
 // implementation for a service that does not allow binding of
-// services.
+// services. Note that the no-op operations report success; a broker
+// implementing only envoy.MinimalBroker and the operations it supports
+// has the unsupported ones answered with a 501 Not Implemented instead.
 type Broker struct {
 	Cataloger
 	Credentialer
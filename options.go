@@ -0,0 +1,412 @@
+package envoy
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/bearer"
+	"github.com/pivotal-cf-experimental/envoy/internal/handlers"
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+	"github.com/pivotal-cf-experimental/envoy/metrics"
+	"github.com/pivotal-cf-experimental/envoy/redact"
+	"github.com/pivotal-cf-experimental/envoy/secrets"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures the http.Handler returned by NewBrokerHandler.
+type Option func(*config)
+
+// Middleware wraps the handler of a route in another http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// ClientKey identifies the client making a request, for rate limiting and
+// lockouts.
+type ClientKey func(req *http.Request) string
+
+type config struct {
+	logger          *slog.Logger
+	redactor        redact.Redactor
+	metrics         *metrics.Registry
+	tracerProvider  trace.TracerProvider
+	propagator      propagation.TextMapPropagator
+	panicHook       middleware.PanicHook
+	secretStore     secrets.Store
+	secretNamespace string
+	maxBodySize     int64
+	strictDecoding  bool
+	maxIDLength     int
+	idPattern       *regexp.Regexp
+	bearerVerifier  *bearer.Verifier
+	certFile        string
+	keyFile         string
+	clientCAFile    string
+	certPolicy      CertificatePolicy
+	rateLimit       float64
+	rateBurst       int
+	lockoutFailures int
+	lockoutDuration time.Duration
+	clientKey       ClientKey
+	concurrency     map[string]int
+	shutdownTimeout time.Duration
+	gate            *middleware.Gate
+	healthEndpoints bool
+	maintenance     *MaintenanceMode
+	middleware      []Middleware
+	routeMiddleware map[string][]Middleware
+	authenticator   Middleware
+	pathPrefix      string
+	routes          []route
+}
+
+// DefaultMaxBodySize is the largest request body accepted by the broker
+// handler unless WithMaxBodySize is given.
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultMaxIDLength is the longest instance or binding ID accepted by the
+// broker handler unless WithMaxIDLength is given.
+const DefaultMaxIDLength = 255
+
+// DefaultIDPattern matches the instance and binding IDs accepted by the
+// broker handler unless WithIDPattern is given: IDs made of letters,
+// digits, and the characters "-", ".", "_" and "~".
+var DefaultIDPattern = handlers.DefaultIDPattern
+
+// GUIDPattern matches IDs formatted as GUIDs. Cloud Foundry always uses
+// GUIDs as instance and binding IDs.
+var GUIDPattern = handlers.GUIDPattern
+
+// DefaultShutdownTimeout is how long Server.Run waits for in-flight
+// requests and background work to finish when shutting down, unless
+// WithShutdownTimeout is given.
+const DefaultShutdownTimeout = 30 * time.Second
+
+func newConfig(options []Option) config {
+	c := config{
+		redactor:        redact.New(),
+		propagator:      propagation.TraceContext{},
+		maxBodySize:     DefaultMaxBodySize,
+		maxIDLength:     DefaultMaxIDLength,
+		idPattern:       DefaultIDPattern,
+		shutdownTimeout: DefaultShutdownTimeout,
+	}
+	for _, option := range options {
+		option(&c)
+	}
+
+	return c
+}
+
+// WithSecretStore stores the credentials returned by the broker's Bind
+// method in the given secret store, and responds to bind requests with a
+// {"credhub-ref": "..."} credential naming the stored secret instead. The
+// namespace, usually the name of the broker, is part of every secret name,
+// and must be a valid segment of it: not empty, "." or "..", and without a
+// "/". Stored secrets are deleted once the binding has been unbound.
+func WithSecretStore(store secrets.Store, namespace string) Option {
+	return func(c *config) {
+		c.secretStore = store
+		c.secretNamespace = namespace
+	}
+}
+
+// WithLogger logs every request served by the broker handler to the given
+// logger, including its method, route, instance and binding IDs, status,
+// latency, API version and originating identity. Requests are not logged
+// unless a logger is configured; recovered panics are logged to the
+// default slog logger instead.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithPanicHook reports panics recovered while serving a request to the
+// given function, along with the request and the stack trace of the
+// panic. Panics are always recovered, logged and answered with a 500
+// response; the hook can be used to forward them to an error tracker.
+func WithPanicHook(hook func(req *http.Request, recovered interface{}, stack []byte)) Option {
+	return func(c *config) {
+		c.panicHook = hook
+	}
+}
+
+// WithRedactor replaces the redactor used to mask sensitive values in
+// logs and error descriptions. By default, a redactor masking the
+// redact.DefaultPaths is used; use this option to declare additional
+// sensitive JSON paths.
+func WithRedactor(redactor redact.Redactor) Option {
+	return func(c *config) {
+		c.redactor = redactor
+	}
+}
+
+// WithMetrics records request counts, error counts, operation latencies
+// and asynchronous operations in flight in the given registry, and serves them in the Prometheus text format on
+// the GET /metrics route. The route requires the same authentication as
+// the rest of the service broker API.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(c *config) {
+		c.metrics = registry
+	}
+}
+
+// WithTracerProvider traces every request with a tracer from the given
+// provider. A server span is started for each request, continuing the
+// trace given in its W3C traceparent header, with child spans around
+// authentication, the parsing of the request and the call to the broker.
+// The context of the broker call span is handed to the broker through the
+// Context method of the request. Use the tracetest.InMemoryExporter of the
+// OpenTelemetry SDK to inspect the spans in tests.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagator replaces the propagator used to extract the trace context
+// from incoming requests. By default, the W3C Trace Context headers are
+// used.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// WithMaxBodySize answers requests whose body is larger than the given
+// number of bytes with a 413 Request Entity Too Large response. By
+// default, bodies are limited to DefaultMaxBodySize; a size of zero
+// removes the limit.
+func WithMaxBodySize(bytes int64) Option {
+	return func(c *config) {
+		c.maxBodySize = bytes
+	}
+}
+
+// WithStrictDecoding answers provision and bind requests whose body
+// contains unknown top-level fields with a 400 Bad Request response
+// naming the field. By default, unknown fields are ignored.
+func WithStrictDecoding() Option {
+	return func(c *config) {
+		c.strictDecoding = true
+	}
+}
+
+// WithIDPattern replaces the pattern that instance and binding IDs must
+// match. IDs are percent-decoded before they are matched, and requests
+// with IDs that do not match are answered with a 400 Bad Request
+// response. Use GUIDPattern to only accept GUIDs, or nil to accept any ID.
+func WithIDPattern(pattern *regexp.Regexp) Option {
+	return func(c *config) {
+		c.idPattern = pattern
+	}
+}
+
+// WithMaxIDLength replaces the maximum length of instance and binding IDs,
+// DefaultMaxIDLength by default. A length of zero removes the limit.
+func WithMaxIDLength(length int) Option {
+	return func(c *config) {
+		c.maxIDLength = length
+	}
+}
+
+// WithBearerAuth authenticates requests with bearer tokens, such as those
+// issued by UAA, instead of the Basic Auth credentials of the broker.
+// Tokens are checked by the given verifier; requests without a valid token
+// are answered with a 401 Unauthorized response, and requests whose token
+// lacks a required scope with a 403 Forbidden response. The claims of the
+// token are available to the broker through bearer.FromContext and the
+// Context method of the request.
+func WithBearerAuth(verifier bearer.Verifier) Option {
+	return func(c *config) {
+		c.bearerVerifier = &verifier
+	}
+}
+
+// WithTLSCertificate makes NewServer serve HTTPS with the certificate and
+// private key in the given PEM files. The files are loaded again whenever
+// they change, so that certificates can be renewed without a restart.
+// The option has no effect on NewBrokerHandler.
+func WithTLSCertificate(certFile, keyFile string) Option {
+	return func(c *config) {
+		c.certFile = certFile
+		c.keyFile = keyFile
+	}
+}
+
+// WithClientCA makes NewServer require clients to present a certificate
+// signed by one of the CA certificates in the given PEM file, enabling
+// mutual TLS. The file is loaded again whenever it changes. The option
+// requires WithTLSCertificate, and has no effect on NewBrokerHandler.
+func WithClientCA(caFile string) Option {
+	return func(c *config) {
+		c.clientCAFile = caFile
+	}
+}
+
+// WithCertificatePolicy only lets requests through when the verified
+// client certificate they were made with is allowed by the policy to call
+// the requested operation; other requests are answered with a 403
+// Forbidden response. Requests must still be authenticated with the
+// credentials of the broker. Client certificates are verified by a Server
+// configured with WithClientCA, or by the caller's own TLS server;
+// NewServer refuses a policy without WithClientCA, since every request
+// would be forbidden.
+func WithCertificatePolicy(policy CertificatePolicy) Option {
+	return func(c *config) {
+		c.certPolicy = policy
+	}
+}
+
+// WithRateLimit limits the rate of requests from each client, identified
+// by its IP address unless WithClientKey is given, to perSecond requests
+// per second on average, with bursts of up to burst requests. Requests
+// over the limit are answered with a 429 Too Many Requests response and a
+// Retry-After header, before their credentials are checked. Requests are
+// not rate limited when perSecond or burst is zero or negative.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *config) {
+		c.rateLimit = perSecond
+		c.rateBurst = burst
+	}
+}
+
+// WithLockout locks clients, identified by their IP address unless
+// WithClientKey is given, out for the given duration once they have failed
+// to authenticate maxFailures times in a row. Requests from locked out
+// clients are answered with a 429 Too Many Requests response and a
+// Retry-After header, whatever their credentials. Clients are never
+// locked out when maxFailures or duration is zero or negative.
+//
+// Behind a router or load balancer, such as the Cloud Foundry gorouter,
+// every request comes from the address of a proxy: anyone sending wrong
+// credentials then locks every client out, Cloud Controller included.
+// Identify clients with ForwardedClientKey, or another key, in that case.
+func WithLockout(maxFailures int, duration time.Duration) Option {
+	return func(c *config) {
+		c.lockoutFailures = maxFailures
+		c.lockoutDuration = duration
+	}
+}
+
+// WithClientKey replaces how clients are identified by WithRateLimit and
+// WithLockout, which is by the IP address of the connection by default.
+func WithClientKey(key ClientKey) Option {
+	return func(c *config) {
+		c.clientKey = key
+	}
+}
+
+// ForwardedClientKey returns a ClientKey identifying clients by the last
+// address of the X-Forwarded-For header that is not within one of the
+// trusted proxy networks. Requests whose connection does not come from a
+// trusted proxy are identified by the address of the connection, so that
+// clients cannot choose their own identity.
+func ForwardedClientKey(trustedProxies ...*net.IPNet) ClientKey {
+	return ClientKey(middleware.ForwardedClient(trustedProxies))
+}
+
+// WithConcurrencyLimit limits the number of requests for the operation,
+// such as "provision" or "bind", served at the same time. Requests over
+// the limit are answered with a 503 Service Unavailable response and a
+// Retry-After header. The option can be given once per operation; a zero
+// or negative limit leaves the operation unlimited.
+func WithConcurrencyLimit(operation string, limit int) Option {
+	return func(c *config) {
+		if c.concurrency == nil {
+			c.concurrency = map[string]int{}
+		}
+		c.concurrency[operation] = limit
+	}
+}
+
+// WithShutdownTimeout replaces how long Server.Run waits for in-flight
+// requests and background work to finish when shutting down. The option
+// has no effect on NewBrokerHandler.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.shutdownTimeout = timeout
+	}
+}
+
+// WithHealthEndpoints serves unauthenticated liveness and readiness probes
+// on GET /healthz and GET /readyz. The liveness probe always succeeds.
+// The readiness probe responds with a 503 Service Unavailable when the
+// catalog of the broker is not valid, when the broker implements
+// HealthChecker and reports an error, or when the Server is shutting
+// down. Neither probe is rate limited or subject to a lockout.
+func WithHealthEndpoints() Option {
+	return func(c *config) {
+		c.healthEndpoints = true
+	}
+}
+
+// WithMaintenanceMode refuses provision, bind, unbind and deprovision
+// requests with a 503 Service Unavailable response while the given
+// maintenance mode is enabled. The maintenance mode can also be read and
+// toggled through the GET and PUT /admin/maintenance routes, named
+// "maintenance" and "set_maintenance", which require the same
+// authentication as the rest of the service broker API. PUT requests take
+// a body such as {"enabled": true, "description": "...", "retry_after": 600}.
+func WithMaintenanceMode(mode *MaintenanceMode) Option {
+	return func(c *config) {
+		c.maintenance = mode
+	}
+}
+
+// WithMiddleware wraps every route in the given middleware, the first of
+// which sees requests first. The middleware runs once the request has been
+// given its ID, traced, logged and measured, and before it is throttled or
+// authenticated. The option can be given more than once.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *config) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithRouteMiddleware wraps the route serving the operation, such as
+// "provision" or "bind", in the given middleware, the first of which sees
+// requests first. The middleware runs after authentication, just before
+// the handler of the route. The option can be given more than once.
+func WithRouteMiddleware(operation string, middleware ...Middleware) Option {
+	return func(c *config) {
+		if c.routeMiddleware == nil {
+			c.routeMiddleware = map[string][]Middleware{}
+		}
+		c.routeMiddleware[operation] = append(c.routeMiddleware[operation], middleware...)
+	}
+}
+
+// WithAuthenticator replaces the authentication of requests with the
+// given middleware, which must answer the requests it refuses itself,
+// usually with a 401 Unauthorized response. It takes precedence over
+// WithBearerAuth and the credentials of the broker.
+func WithAuthenticator(authenticator Middleware) Option {
+	return func(c *config) {
+		c.authenticator = authenticator
+	}
+}
+
+// WithPathPrefix mounts every route under the given prefix, such as
+// "/broker", so that the catalog is served on GET /broker/v2/catalog.
+func WithPathPrefix(prefix string) Option {
+	return func(c *config) {
+		c.pathPrefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// WithRoute serves the handler for the method and path, next to the
+// service broker API. The route is named after the operation, and goes
+// through the same middleware as the rest of the API, authentication
+// included. Path variables are declared as in gorilla/mux, such as
+// "/v2/service_instances/{instance_id}/backups". The option can be given
+// more than once.
+func WithRoute(operation, method, path string, handler http.Handler) Option {
+	return func(c *config) {
+		c.routes = append(c.routes, route{operation, method, path, handler})
+	}
+}
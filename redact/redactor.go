@@ -0,0 +1,195 @@
+package redact
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
+
+// Mask replaces every redacted value.
+const Mask = "[REDACTED]"
+
+// DefaultPaths are the JSON paths of the fields of the service broker API
+// that always hold sensitive values: binding credentials, the dashboard
+// client secret, and the arbitrary parameters given to provision, update
+// and bind requests.
+var DefaultPaths = []string{
+	"credentials",
+	"dashboard_client.secret",
+	"parameters",
+}
+
+var defaultKeys = []string{"password", "passwd", "secret", "token", "authorization"}
+
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+var (
+	userinfoExpression = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/?#@\s]*):[^@/?#\s]*@`)
+	schemeExpression   = regexp.MustCompile(`(?i)\b(basic|bearer)\s+[a-zA-Z0-9._~+/=-]+`)
+)
+
+// Redactor masks sensitive values in JSON documents, strings, headers and
+// log attributes before they are logged, recorded or returned in error
+// descriptions.
+//
+// A path is a dot-separated list of JSON object keys, where "*" matches
+// any single key or array element. Paths match the trailing keys of a
+// field, so "dashboard_client.secret" masks the secret of every dashboard
+// client in a catalog.
+type Redactor struct {
+	paths    [][]string
+	keyMatch *regexp.Regexp
+}
+
+// New returns a Redactor masking the DefaultPaths and the given
+// user-declared JSON paths.
+func New(paths ...string) Redactor {
+	var r Redactor
+	keys := append([]string{}, defaultKeys...)
+	for _, path := range append(append([]string{}, DefaultPaths...), paths...) {
+		segments := strings.Split(path, ".")
+		r.paths = append(r.paths, segments)
+
+		if last := segments[len(segments)-1]; last != "*" {
+			keys = append(keys, strings.ToLower(last))
+		}
+	}
+
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = regexp.QuoteMeta(key)
+	}
+
+	r.keyMatch = regexp.MustCompile(`(?i)("?(?:` + strings.Join(quoted, "|") + `)"?\s*[:=]\s*)("[^"]*"|[^\s,&;}]+)`)
+
+	return r
+}
+
+// JSON returns the document with every sensitive field masked. Documents
+// that are not valid JSON are redacted as strings.
+func (r Redactor) JSON(document []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(document, &value); err != nil {
+		return []byte(r.String(string(document)))
+	}
+
+	redacted, err := json.Marshal(r.value(nil, value))
+	if err != nil {
+		return []byte(Mask)
+	}
+
+	return redacted
+}
+
+// String masks credentials embedded in free text: passwords in URLs,
+// Basic and Bearer authorization values, and key/value pairs whose key
+// names a sensitive field.
+func (r Redactor) String(s string) string {
+	s = userinfoExpression.ReplaceAllString(s, "$1:"+Mask+"@")
+	s = schemeExpression.ReplaceAllString(s, "$1 "+Mask)
+	return r.keyMatch.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Header returns a copy of the header with authorization values masked.
+func (r Redactor) Header(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if _, ok := redacted[name]; ok {
+			redacted.Set(name, Mask)
+		}
+	}
+
+	return redacted
+}
+
+// BindResponse returns a copy of the response with the value of every
+// credential masked. Credential keys are kept.
+func (r Redactor) BindResponse(response domain.BindResponse) domain.BindResponse {
+	if response.Credentials == nil {
+		return response
+	}
+
+	credentials := make(domain.BindingCredentials, len(response.Credentials))
+	for key := range response.Credentials {
+		credentials[key] = Mask
+	}
+	response.Credentials = credentials
+
+	return response
+}
+
+// DashboardClient returns a copy of the dashboard client with its secret
+// masked.
+func (r Redactor) DashboardClient(client domain.DashboardClient) domain.DashboardClient {
+	if client.Secret != "" {
+		client.Secret = Mask
+	}
+
+	return client
+}
+
+// ReplaceAttr can be used as the ReplaceAttr function of
+// slog.HandlerOptions. It masks attributes whose key path matches a
+// sensitive path, and redacts every string value.
+func (r Redactor) ReplaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if r.matches(append(append([]string{}, groups...), attr.Key)) {
+		return slog.String(attr.Key, Mask)
+	}
+
+	if attr.Value.Kind() == slog.KindString {
+		return slog.String(attr.Key, r.String(attr.Value.String()))
+	}
+
+	return attr
+}
+
+func (r Redactor) value(location []string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			childLocation := append(location[:len(location):len(location)], key)
+			if r.matches(childLocation) {
+				value[key] = Mask
+				continue
+			}
+			value[key] = r.value(childLocation, child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = r.value(append(location[:len(location):len(location)], "*"), child)
+		}
+	case string:
+		return r.String(value)
+	}
+
+	return value
+}
+
+func (r Redactor) matches(location []string) bool {
+	for _, path := range r.paths {
+		if matchesSuffix(path, location) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesSuffix(path, location []string) bool {
+	if len(path) > len(location) {
+		return false
+	}
+
+	offset := len(location) - len(path)
+	for i, segment := range path {
+		if segment != "*" && !strings.EqualFold(segment, location[offset+i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,90 @@
+package envoy
+
+import (
+	"errors"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/redact"
+)
+
+// The redacting operations wrap the broker, masking sensitive values in
+// the messages of the errors it returns before they are used as error
+// descriptions in responses.
+
+type redactingProvisioner struct {
+	Provisioner
+	redactor redact.Redactor
+}
+
+func (p redactingProvisioner) Provision(request domain.ProvisionRequest) (domain.ProvisionResponse, error) {
+	response, err := p.Provisioner.Provision(request)
+	return response, redactError(p.redactor, err)
+}
+
+type redactingBinder struct {
+	Binder
+	redactor redact.Redactor
+}
+
+func (b redactingBinder) Bind(request domain.BindRequest) (domain.BindResponse, error) {
+	response, err := b.Binder.Bind(request)
+	return response, redactError(b.redactor, err)
+}
+
+type redactingUnbinder struct {
+	Unbinder
+	redactor redact.Redactor
+}
+
+func (u redactingUnbinder) Unbind(request domain.UnbindRequest) error {
+	return redactError(u.redactor, u.Unbinder.Unbind(request))
+}
+
+type redactingDeprovisioner struct {
+	AsyncDeprovisioner
+	redactor redact.Redactor
+}
+
+func (d redactingDeprovisioner) DeprovisionAsync(request domain.DeprovisionRequest) (domain.DeprovisionResponse, error) {
+	response, err := d.AsyncDeprovisioner.DeprovisionAsync(request)
+	return response, redactError(d.redactor, err)
+}
+
+type redactingLastOperationer struct {
+	LastOperationer
+	redactor redact.Redactor
+}
+
+func (l redactingLastOperationer) LastOperation(request domain.LastOperationRequest) (domain.LastOperationResponse, error) {
+	response, err := l.LastOperationer.LastOperation(request)
+	return response, redactError(l.redactor, err)
+}
+
+// redactError masks sensitive values in the message of err. Errors of the
+// domain package, even wrapped, keep their type, so that they are still
+// answered with the response the service broker API expects.
+func redactError(redactor redact.Redactor, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := redactor.String(err.Error())
+	if message == err.Error() {
+		return err
+	}
+
+	switch {
+	case errors.As(err, new(domain.ServiceInstanceAlreadyExistsError)):
+		return domain.ServiceInstanceAlreadyExistsError(message)
+	case errors.As(err, new(domain.ServiceInstanceNotFoundError)):
+		return domain.ServiceInstanceNotFoundError(message)
+	case errors.As(err, new(domain.ServiceBindingAlreadyExistsError)):
+		return domain.ServiceBindingAlreadyExistsError(message)
+	case errors.As(err, new(domain.ServiceBindingNotFoundError)):
+		return domain.ServiceBindingNotFoundError(message)
+	case errors.As(err, new(domain.ConcurrencyError)):
+		return domain.ConcurrencyError(message)
+	default:
+		return errors.New(message)
+	}
+}
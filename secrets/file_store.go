@@ -0,0 +1,89 @@
+package secrets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
+
+// FileStore is a Store that keeps each secret as a JSON document in a
+// directory on the local filesystem. Files are only readable by the
+// owner of the process. It is meant to be used in tests and local
+// development.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore writing to the given directory,
+// creating it if it does not exist.
+func NewFileStore(dir string) (FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return FileStore{}, err
+	}
+
+	return FileStore{dir: dir}, nil
+}
+
+// Put stores the credentials under the given name, replacing any existing
+// secret.
+func (s FileStore) Put(name string, credentials domain.BindingCredentials) error {
+	document, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(s.dir, ".secret-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(document); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), s.path(name))
+}
+
+// Get returns the credentials stored under the given name, or a
+// NotFoundError.
+func (s FileStore) Get(name string) (domain.BindingCredentials, error) {
+	document, err := ioutil.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, NotFoundError(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials domain.BindingCredentials
+	if err := json.Unmarshal(document, &credentials); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// Delete removes the secret stored under the given name, or returns a
+// NotFoundError.
+func (s FileStore) Delete(name string) error {
+	err := os.Remove(s.path(name))
+	if os.IsNotExist(err) {
+		return NotFoundError(name)
+	}
+
+	return err
+}
+
+func (s FileStore) path(name string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+".json")
+}
@@ -0,0 +1,64 @@
+package secrets_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/secrets"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileStore", func() {
+	var dir string
+	var store secrets.FileStore
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "secrets")
+		Expect(err).NotTo(HaveOccurred())
+
+		store, err = secrets.NewFileStore(filepath.Join(dir, "store"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("stores secrets as files only readable by the owner", func() {
+		Expect(store.Put("/c/broker/service/binding/credentials", domain.BindingCredentials{
+			"password": "pass",
+		})).To(Succeed())
+
+		files, err := ioutil.ReadDir(filepath.Join(dir, "store"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		credentials, err := store.Get("/c/broker/service/binding/credentials")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(domain.BindingCredentials{"password": "pass"}))
+	})
+
+	It("replaces existing secrets", func() {
+		Expect(store.Put("secret", domain.BindingCredentials{"password": "old"})).To(Succeed())
+		Expect(store.Put("secret", domain.BindingCredentials{"password": "new"})).To(Succeed())
+
+		credentials, err := store.Get("secret")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(domain.BindingCredentials{"password": "new"}))
+	})
+
+	It("deletes secrets", func() {
+		Expect(store.Put("secret", domain.BindingCredentials{})).To(Succeed())
+		Expect(store.Delete("secret")).To(Succeed())
+
+		_, err := store.Get("secret")
+		Expect(err).To(Equal(secrets.NotFoundError("secret")))
+		Expect(store.Delete("secret")).To(Equal(secrets.NotFoundError("secret")))
+	})
+})
@@ -0,0 +1,147 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
+
+// ReferenceKey is the key of the single credential returned in place of
+// the real credentials of a service binding.
+const ReferenceKey = "credhub-ref"
+
+type binder interface {
+	Bind(domain.BindRequest) (domain.BindResponse, error)
+}
+
+type unbinder interface {
+	Unbind(domain.UnbindRequest) error
+}
+
+// InvalidReferenceError is an error type used to indicate that a part of
+// the name of a secret would escape the namespace of the broker.
+type InvalidReferenceError string
+
+// Error returns a string representation of the error message.
+func (e InvalidReferenceError) Error() string {
+	return "secrets: invalid secret name segment " + string(e)
+}
+
+// Reference returns the name under which the credentials of a service
+// binding are stored, following the CredHub naming convention of
+// /c/<namespace>/<service id>/<binding id>/credentials. It returns an
+// InvalidReferenceError if a part is empty, ".", "..", or contains a "/",
+// since the service ID and binding ID are given by the platform.
+func Reference(namespace, serviceID, bindingID string) (string, error) {
+	for _, segment := range []string{namespace, serviceID, bindingID} {
+		if err := checkSegment(segment); err != nil {
+			return "", err
+		}
+	}
+
+	return path.Join("/c", namespace, serviceID, bindingID, "credentials"), nil
+}
+
+// CheckNamespace returns an InvalidReferenceError if no secret can be
+// stored under the namespace, so that it can be rejected before any
+// binding is created.
+func CheckNamespace(namespace string) error {
+	return checkSegment(namespace)
+}
+
+func checkSegment(segment string) error {
+	if segment == "" || segment == "." || segment == ".." || strings.Contains(segment, "/") {
+		return InvalidReferenceError(fmt.Sprintf("%q", segment))
+	}
+
+	return nil
+}
+
+// ReferenceBinder wraps a binder, moving the credentials it returns into a
+// Store and replacing them in the bind response with a reference to the
+// stored secret.
+type ReferenceBinder struct {
+	binder
+	store     Store
+	namespace string
+}
+
+// NewReferenceBinder returns a ReferenceBinder storing the credentials
+// returned by binder in the given store.
+func NewReferenceBinder(binder binder, store Store, namespace string) ReferenceBinder {
+	return ReferenceBinder{
+		binder:    binder,
+		store:     store,
+		namespace: namespace,
+	}
+}
+
+// Bind calls the wrapped binder and stores the credentials it returns.
+// Bindings without credentials are returned unchanged. Requests whose
+// service or binding ID cannot be part of a secret name fail before the
+// wrapped binder is called.
+func (b ReferenceBinder) Bind(request domain.BindRequest) (domain.BindResponse, error) {
+	name, err := Reference(b.namespace, request.ServiceID, request.BindingID)
+	if err != nil {
+		return domain.BindResponse{}, err
+	}
+
+	response, err := b.binder.Bind(request)
+	if err != nil || len(response.Credentials) == 0 {
+		return response, err
+	}
+
+	if err := b.store.Put(name, response.Credentials); err != nil {
+		return domain.BindResponse{}, err
+	}
+
+	response.Credentials = domain.BindingCredentials{
+		ReferenceKey: name,
+	}
+
+	return response, nil
+}
+
+// ReferenceUnbinder wraps an unbinder, removing the stored credentials of
+// a service binding once it has been unbound.
+type ReferenceUnbinder struct {
+	unbinder
+	store     Store
+	namespace string
+}
+
+// NewReferenceUnbinder returns a ReferenceUnbinder removing secrets from
+// the given store.
+func NewReferenceUnbinder(unbinder unbinder, store Store, namespace string) ReferenceUnbinder {
+	return ReferenceUnbinder{
+		unbinder:  unbinder,
+		store:     store,
+		namespace: namespace,
+	}
+}
+
+// Unbind calls the wrapped unbinder and, if it succeeds or reports that
+// the binding no longer exists, deletes the stored credentials, so that
+// they do not outlive the binding. Bindings without stored credentials
+// are ignored.
+func (u ReferenceUnbinder) Unbind(request domain.UnbindRequest) error {
+	unbindErr := u.unbinder.Unbind(request)
+	if unbindErr != nil && !errors.As(unbindErr, new(domain.ServiceBindingNotFoundError)) {
+		return unbindErr
+	}
+
+	name, err := Reference(u.namespace, request.ServiceID, request.BindingID)
+	if err != nil {
+		return unbindErr
+	}
+
+	err = u.store.Delete(name)
+	if _, ok := err.(NotFoundError); ok || err == nil {
+		return unbindErr
+	}
+
+	return err
+}
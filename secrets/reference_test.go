@@ -0,0 +1,184 @@
+package secrets_test
+
+import (
+	"errors"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/secrets"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Binder struct {
+	Credentials domain.BindingCredentials
+	Error       error
+}
+
+func (b Binder) Bind(domain.BindRequest) (domain.BindResponse, error) {
+	return domain.BindResponse{
+		Credentials:    b.Credentials,
+		SyslogDrainURL: "syslog://drain",
+	}, b.Error
+}
+
+type BinderFunc func(domain.BindRequest) (domain.BindResponse, error)
+
+func (f BinderFunc) Bind(request domain.BindRequest) (domain.BindResponse, error) {
+	return f(request)
+}
+
+type Unbinder struct {
+	Error error
+}
+
+func (u Unbinder) Unbind(domain.UnbindRequest) error {
+	return u.Error
+}
+
+var _ = Describe("Reference", func() {
+	It("follows the CredHub naming convention", func() {
+		Expect(secrets.Reference("my-broker", "service-id", "binding-id")).To(Equal("/c/my-broker/service-id/binding-id/credentials"))
+	})
+
+	It("refuses names escaping the namespace", func() {
+		for _, segments := range [][]string{
+			{"my-broker", "..", "binding-id"},
+			{"my-broker", "service-id", "."},
+			{"my-broker", "service-id", "../../other-broker"},
+			{"my-broker", "", "binding-id"},
+		} {
+			_, err := secrets.Reference(segments[0], segments[1], segments[2])
+			Expect(err).To(BeAssignableToTypeOf(secrets.InvalidReferenceError("")))
+		}
+	})
+})
+
+var _ = Describe("CheckNamespace", func() {
+	It("accepts a single segment", func() {
+		Expect(secrets.CheckNamespace("my-broker")).To(Succeed())
+	})
+
+	It("refuses namespaces under which no secret can be stored", func() {
+		for _, namespace := range []string{"", ".", "..", "my/broker"} {
+			Expect(secrets.CheckNamespace(namespace)).To(BeAssignableToTypeOf(secrets.InvalidReferenceError("")), namespace)
+		}
+	})
+})
+
+var _ = Describe("ReferenceBinder", func() {
+	var store *secrets.MemoryStore
+	var request domain.BindRequest
+
+	BeforeEach(func() {
+		store = secrets.NewMemoryStore()
+		request = domain.BindRequest{
+			BindingID: "binding-id",
+			ServiceID: "service-id",
+		}
+	})
+
+	It("stores the credentials and returns a reference to them", func() {
+		binder := secrets.NewReferenceBinder(Binder{
+			Credentials: domain.BindingCredentials{"password": "pass"},
+		}, store, "my-broker")
+
+		response, err := binder.Bind(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(Equal(domain.BindResponse{
+			Credentials: domain.BindingCredentials{
+				"credhub-ref": "/c/my-broker/service-id/binding-id/credentials",
+			},
+			SyslogDrainURL: "syslog://drain",
+		}))
+
+		credentials, err := store.Get("/c/my-broker/service-id/binding-id/credentials")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(domain.BindingCredentials{"password": "pass"}))
+	})
+
+	It("does not store anything when there are no credentials", func() {
+		binder := secrets.NewReferenceBinder(Binder{}, store, "my-broker")
+
+		response, err := binder.Bind(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Credentials).To(BeEmpty())
+
+		_, err = store.Get("/c/my-broker/service-id/binding-id/credentials")
+		Expect(err).To(BeAssignableToTypeOf(secrets.NotFoundError("")))
+	})
+
+	It("refuses service IDs escaping the namespace before binding", func() {
+		called := false
+		binder := secrets.NewReferenceBinder(BinderFunc(func(domain.BindRequest) (domain.BindResponse, error) {
+			called = true
+			return domain.BindResponse{}, nil
+		}), store, "my-broker")
+		request.ServiceID = ".."
+
+		_, err := binder.Bind(request)
+		Expect(err).To(BeAssignableToTypeOf(secrets.InvalidReferenceError("")))
+		Expect(called).To(BeFalse())
+	})
+
+	It("does not store anything when the bind fails", func() {
+		binder := secrets.NewReferenceBinder(Binder{
+			Credentials: domain.BindingCredentials{"password": "pass"},
+			Error:       errors.New("BANG!"),
+		}, store, "my-broker")
+
+		_, err := binder.Bind(request)
+		Expect(err).To(MatchError("BANG!"))
+
+		_, err = store.Get("/c/my-broker/service-id/binding-id/credentials")
+		Expect(err).To(BeAssignableToTypeOf(secrets.NotFoundError("")))
+	})
+})
+
+var _ = Describe("ReferenceUnbinder", func() {
+	var store *secrets.MemoryStore
+	var request domain.UnbindRequest
+
+	BeforeEach(func() {
+		store = secrets.NewMemoryStore()
+		request = domain.UnbindRequest{
+			BindingID: "binding-id",
+			ServiceID: "service-id",
+		}
+		Expect(store.Put("/c/my-broker/service-id/binding-id/credentials", domain.BindingCredentials{})).To(Succeed())
+	})
+
+	It("deletes the stored credentials once unbound", func() {
+		unbinder := secrets.NewReferenceUnbinder(Unbinder{}, store, "my-broker")
+
+		Expect(unbinder.Unbind(request)).To(Succeed())
+
+		_, err := store.Get("/c/my-broker/service-id/binding-id/credentials")
+		Expect(err).To(BeAssignableToTypeOf(secrets.NotFoundError("")))
+	})
+
+	It("keeps the stored credentials when the unbind fails", func() {
+		unbinder := secrets.NewReferenceUnbinder(Unbinder{Error: errors.New("BANG!")}, store, "my-broker")
+
+		Expect(unbinder.Unbind(request)).To(MatchError("BANG!"))
+
+		_, err := store.Get("/c/my-broker/service-id/binding-id/credentials")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("deletes the stored credentials when the binding is already gone", func() {
+		gone := domain.ServiceBindingNotFoundError("binding-id")
+		unbinder := secrets.NewReferenceUnbinder(Unbinder{Error: gone}, store, "my-broker")
+
+		Expect(unbinder.Unbind(request)).To(Equal(gone))
+
+		_, err := store.Get("/c/my-broker/service-id/binding-id/credentials")
+		Expect(err).To(BeAssignableToTypeOf(secrets.NotFoundError("")))
+	})
+
+	It("succeeds when there were no stored credentials", func() {
+		unbinder := secrets.NewReferenceUnbinder(Unbinder{}, store, "another-broker")
+
+		Expect(unbinder.Unbind(request)).To(Succeed())
+	})
+})
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"sync"
+
+	"github.com/pivotal-cf-experimental/envoy/domain"
+)
+
+// NotFoundError is an error type used to indicate that no secret is
+// stored under the requested name.
+type NotFoundError string
+
+// Error returns a string representation of the error message.
+func (e NotFoundError) Error() string {
+	return "secrets: no secret stored for " + string(e)
+}
+
+// Store defines the interface for a secret store holding the credentials
+// of service bindings on behalf of the broker. Delete must return a
+// NotFoundError when no secret is stored under the name.
+type Store interface {
+	Put(name string, credentials domain.BindingCredentials) error
+	Get(name string) (domain.BindingCredentials, error)
+	Delete(name string) error
+}
+
+// MemoryStore is a Store that keeps secrets in memory. It is meant to be
+// used in tests and local development, and is safe for concurrent use.
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	secrets map[string]domain.BindingCredentials
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		secrets: map[string]domain.BindingCredentials{},
+	}
+}
+
+// Put stores the credentials under the given name, replacing any existing
+// secret.
+func (s *MemoryStore) Put(name string, credentials domain.BindingCredentials) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.secrets[name] = credentials
+	return nil
+}
+
+// Get returns the credentials stored under the given name, or a
+// NotFoundError.
+func (s *MemoryStore) Get(name string) (domain.BindingCredentials, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	credentials, ok := s.secrets[name]
+	if !ok {
+		return nil, NotFoundError(name)
+	}
+
+	return credentials, nil
+}
+
+// Delete removes the secret stored under the given name, or returns a
+// NotFoundError.
+func (s *MemoryStore) Delete(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.secrets[name]; !ok {
+		return NotFoundError(name)
+	}
+
+	delete(s.secrets, name)
+	return nil
+}
@@ -0,0 +1,37 @@
+package secrets_test
+
+import (
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/secrets"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryStore", func() {
+	var store *secrets.MemoryStore
+
+	BeforeEach(func() {
+		store = secrets.NewMemoryStore()
+	})
+
+	It("stores and returns secrets by name", func() {
+		Expect(store.Put("/c/broker/secret", domain.BindingCredentials{"password": "pass"})).To(Succeed())
+
+		credentials, err := store.Get("/c/broker/secret")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(domain.BindingCredentials{"password": "pass"}))
+	})
+
+	It("deletes secrets", func() {
+		Expect(store.Put("/c/broker/secret", domain.BindingCredentials{})).To(Succeed())
+		Expect(store.Delete("/c/broker/secret")).To(Succeed())
+
+		_, err := store.Get("/c/broker/secret")
+		Expect(err).To(Equal(secrets.NotFoundError("/c/broker/secret")))
+	})
+
+	It("returns a NotFoundError when deleting a missing secret", func() {
+		Expect(store.Delete("missing")).To(Equal(secrets.NotFoundError("missing")))
+	})
+})
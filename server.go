@@ -0,0 +1,179 @@
+package envoy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy/internal/middleware"
+)
+
+// Server serves the service broker API for a broker. It serves HTTPS when
+// a certificate is configured with WithTLSCertificate, and plain HTTP
+// otherwise.
+type Server struct {
+	server          *http.Server
+	certificates    *certificateLoader
+	broker          MinimalBroker
+	gate            *middleware.Gate
+	shutdownTimeout time.Duration
+}
+
+// NewServer returns a Server listening on addr, serving the handler
+// returned by NewBrokerHandler for the broker and options. It returns an
+// error when the configured certificates cannot be loaded, when a
+// certificate policy is configured without WithClientCA, when the
+// namespace given to WithSecretStore is invalid, or when the broker
+// accepts a password hash that cannot be verified.
+func NewServer(addr string, broker MinimalBroker, options ...Option) (*Server, error) {
+	gate := middleware.NewGate()
+	options = append(options[:len(options):len(options)], func(c *config) {
+		c.gate = gate
+	})
+	config := newConfig(options)
+
+	if config.clientCAFile != "" && config.certFile == "" {
+		return nil, errors.New("envoy: client certificates can only be verified when serving TLS")
+	}
+
+	if config.certPolicy != nil && config.clientCAFile == "" {
+		return nil, errors.New("envoy: a certificate policy requires client certificates to be verified with WithClientCA")
+	}
+
+	if err := checkConfig(broker, config); err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           NewBrokerHandler(broker, options...),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		broker:          broker,
+		gate:            gate,
+		shutdownTimeout: config.shutdownTimeout,
+	}
+
+	if config.certFile != "" {
+		logger := config.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+
+		certificates, err := newCertificateLoader(config.certFile, config.keyFile, config.clientCAFile, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		server.certificates = certificates
+		server.server.TLSConfig = &tls.Config{
+			GetConfigForClient: certificates.config,
+		}
+	}
+
+	return server, nil
+}
+
+// Run listens on the address of the server and serves requests until the
+// context is done or the process receives SIGTERM or SIGINT. It then
+// shuts the server down gracefully, waiting at most the shutdown timeout,
+// and returns nil once it is done.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	return s.Shutdown(shutdownCtx)
+}
+
+// Shutdown gracefully shuts the server down. New provision, bind, unbind
+// and deprovision requests are answered with a 503 Service Unavailable
+// response and a Retry-After header, while catalog requests are still
+// served. Once the requests in flight have been served, the broker is
+// drained if it implements Drainer, and the server is closed. Shutdown
+// returns the context's error if it is done first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.gate.Wait(ctx); err != nil {
+		s.server.Close()
+		return err
+	}
+
+	if drainer, ok := s.broker.(Drainer); ok {
+		if err := drainer.Drain(ctx); err != nil {
+			s.server.Close()
+			return err
+		}
+	}
+
+	return s.server.Shutdown(ctx)
+}
+
+// ListenAndServe listens on the address of the server and serves
+// requests until the server is closed.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve serves requests accepted by the listener until the server is
+// closed. It returns nil when the server was shut down gracefully.
+func (s *Server) Serve(listener net.Listener) error {
+	var err error
+	if s.certificates != nil {
+		err = s.server.ServeTLS(listener, "", "")
+	} else {
+		err = s.server.Serve(listener)
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// ReloadCertificates loads the configured certificates again. Changed
+// certificate files are also picked up automatically on the next TLS
+// handshake.
+func (s *Server) ReloadCertificates() error {
+	if s.certificates == nil {
+		return nil
+	}
+
+	return s.certificates.load()
+}
+
+// Close immediately closes the listeners and connections of the server.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
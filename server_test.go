@@ -0,0 +1,346 @@
+package envoy_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf-experimental/envoy"
+	"github.com/pivotal-cf-experimental/envoy/domain"
+	"github.com/pivotal-cf-experimental/envoy/secrets"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	der         []byte
+}
+
+func newTestCertificate(commonName string, serial int64, parent *testCertificate) testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	certificate, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	return testCertificate{certificate: certificate, key: key, der: der}
+}
+
+func (c testCertificate) write(dir, name string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	Expect(err).NotTo(HaveOccurred())
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	Expect(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)).To(Succeed())
+
+	return certFile, keyFile
+}
+
+func (c testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+type DrainingBroker struct {
+	*TestBroker
+	Started chan struct{}
+	Release chan struct{}
+	Drained bool
+}
+
+func (broker *DrainingBroker) Provision(request domain.ProvisionRequest) (domain.ProvisionResponse, error) {
+	broker.Started <- struct{}{}
+	<-broker.Release
+	return domain.ProvisionResponse{}, nil
+}
+
+func (broker *DrainingBroker) Drain(ctx context.Context) error {
+	broker.Drained = true
+	return nil
+}
+
+var _ = Describe("Server", func() {
+	var dir string
+	var ca, serverCertificate, clientCertificate testCertificate
+	var certFile, keyFile, caFile string
+	var listener net.Listener
+	var server *envoy.Server
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "envoy-server")
+		Expect(err).NotTo(HaveOccurred())
+
+		ca = newTestCertificate("ca", 1, nil)
+		serverCertificate = newTestCertificate("broker", 2, &ca)
+		clientCertificate = newTestCertificate("cloud-controller", 3, &ca)
+
+		certFile, keyFile = serverCertificate.write(dir, "server")
+		caFile, _ = ca.write(dir, "ca")
+
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Close()
+		}
+		os.RemoveAll(dir)
+	})
+
+	serve := func(options ...envoy.Option) {
+		var err error
+		server, err = envoy.NewServer(listener.Addr().String(), NewTestBroker(), options...)
+		Expect(err).NotTo(HaveOccurred())
+
+		go server.Serve(listener)
+	}
+
+	client := func(certificates ...tls.Certificate) *http.Client {
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.certificate)
+
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      roots,
+					Certificates: certificates,
+				},
+				DisableKeepAlives: true,
+			},
+		}
+	}
+
+	get := func(client *http.Client, path string) (*http.Response, error) {
+		request, err := http.NewRequest("GET", "https://"+listener.Addr().String()+path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.SetBasicAuth("username", "password")
+
+		return client.Do(request)
+	}
+
+	It("serves the broker API over TLS", func() {
+		serve(envoy.WithTLSCertificate(certFile, keyFile))
+
+		response, err := get(client(), "/v2/catalog")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.TLS.PeerCertificates[0].SerialNumber.Int64()).To(Equal(int64(2)))
+	})
+
+	It("picks up renewed certificates without a restart", func() {
+		serve(envoy.WithTLSCertificate(certFile, keyFile))
+
+		_, err := get(client(), "/v2/catalog")
+		Expect(err).NotTo(HaveOccurred())
+
+		renewed := newTestCertificate("broker", 4, &ca)
+		renewed.write(dir, "server")
+		future := time.Now().Add(time.Minute)
+		Expect(os.Chtimes(certFile, future, future)).To(Succeed())
+
+		response, err := get(client(), "/v2/catalog")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.TLS.PeerCertificates[0].SerialNumber.Int64()).To(Equal(int64(4)))
+	})
+
+	It("fails to start when the certificate cannot be loaded", func() {
+		_, err := envoy.NewServer("127.0.0.1:0", NewTestBroker(), envoy.WithTLSCertificate(certFile, filepath.Join(dir, "missing.key")))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("refuses to verify client certificates without TLS", func() {
+		_, err := envoy.NewServer("127.0.0.1:0", NewTestBroker(), envoy.WithClientCA(caFile))
+		Expect(err).To(MatchError("envoy: client certificates can only be verified when serving TLS"))
+	})
+
+	It("refuses a certificate policy without verifying client certificates", func() {
+		_, err := envoy.NewServer("127.0.0.1:0", NewTestBroker(),
+			envoy.WithTLSCertificate(certFile, keyFile),
+			envoy.WithCertificatePolicy(envoy.CertificatePolicy{}),
+		)
+		Expect(err).To(MatchError("envoy: a certificate policy requires client certificates to be verified with WithClientCA"))
+	})
+
+	It("refuses a secret store namespace under which no secret can be stored", func() {
+		_, err := envoy.NewServer("127.0.0.1:0", NewTestBroker(), envoy.WithSecretStore(secrets.NewMemoryStore(), "../other-broker"))
+		Expect(err).To(MatchError(ContainSubstring("envoy: the namespace of the secret store is invalid")))
+	})
+
+	It("refuses password hashes that cannot be verified", func() {
+		_, err := envoy.NewServer("127.0.0.1:0", HashingBroker{NewTestBroker(), "$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdA$a2V5"})
+		Expect(err).To(MatchError(ContainSubstring(`envoy: the password hash of "username" cannot be verified`)))
+	})
+
+	Context("when client certificates are required", func() {
+		BeforeEach(func() {
+			serve(
+				envoy.WithTLSCertificate(certFile, keyFile),
+				envoy.WithClientCA(caFile),
+				envoy.WithCertificatePolicy(envoy.CertificatePolicy{
+					{CommonName: "cloud-controller", Operations: []string{"catalog"}},
+				}),
+			)
+		})
+
+		It("rejects clients without a certificate", func() {
+			_, err := get(client(), "/v2/catalog")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects clients with a certificate signed by another CA", func() {
+			otherCA := newTestCertificate("other-ca", 5, nil)
+			impostor := newTestCertificate("cloud-controller", 6, &otherCA)
+
+			_, err := get(client(impostor.tlsCertificate()), "/v2/catalog")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("lets clients call the operations allowed by the policy", func() {
+			response, err := get(client(clientCertificate.tlsCertificate()), "/v2/catalog")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("forbids other operations", func() {
+			request, err := http.NewRequest("PUT", "https://"+listener.Addr().String()+"/v2/service_instances/banana", strings.NewReader(`{}`))
+			Expect(err).NotTo(HaveOccurred())
+			request.SetBasicAuth("username", "password")
+
+			response, err := client(clientCertificate.tlsCertificate()).Do(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("when shutting down", func() {
+		var broker *DrainingBroker
+
+		BeforeEach(func() {
+			broker = &DrainingBroker{
+				TestBroker: NewTestBroker(),
+				Started:    make(chan struct{}, 1),
+				Release:    make(chan struct{}),
+			}
+
+			var err error
+			server, err = envoy.NewServer(listener.Addr().String(), broker)
+			Expect(err).NotTo(HaveOccurred())
+			go server.Serve(listener)
+		})
+
+		request := func(method, path, body string) (*http.Response, error) {
+			request, err := http.NewRequest(method, "http://"+listener.Addr().String()+path, strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			request.SetBasicAuth("username", "password")
+
+			return http.DefaultClient.Do(request)
+		}
+
+		It("drains requests in flight and the broker, refusing new mutating requests", func() {
+			provisioned := make(chan int, 1)
+			go func() {
+				defer GinkgoRecover()
+				response, err := request("PUT", "/v2/service_instances/banana",
+					`{"service_id":"s","plan_id":"p","organization_guid":"o","space_guid":"s"}`)
+				Expect(err).NotTo(HaveOccurred())
+				provisioned <- response.StatusCode
+			}()
+			<-broker.Started
+
+			shutdown := make(chan error, 1)
+			go func() {
+				shutdown <- server.Shutdown(context.Background())
+			}()
+
+			Eventually(func() int {
+				response, err := request("DELETE", "/v2/service_instances/apple?service_id=s&plan_id=p", "")
+				Expect(err).NotTo(HaveOccurred())
+				return response.StatusCode
+			}).Should(Equal(http.StatusServiceUnavailable))
+
+			response, err := request("GET", "/v2/catalog", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(broker.Drained).To(BeFalse())
+
+			close(broker.Release)
+			Eventually(provisioned).Should(Receive(Equal(http.StatusCreated)))
+			Eventually(shutdown).Should(Receive(BeNil()))
+			Expect(broker.Drained).To(BeTrue())
+		})
+
+		It("gives up once the deadline has passed", func() {
+			go request("PUT", "/v2/service_instances/banana",
+				`{"service_id":"s","plan_id":"p","organization_guid":"o","space_guid":"s"}`)
+			<-broker.Started
+			defer close(broker.Release)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			Expect(server.Shutdown(ctx)).To(Equal(context.DeadlineExceeded))
+			Expect(broker.Drained).To(BeFalse())
+		})
+	})
+
+	It("runs until its context is done", func() {
+		listener.Close()
+		var err error
+		server, err = envoy.NewServer(listener.Addr().String(), NewTestBroker(), envoy.WithShutdownTimeout(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ran := make(chan error, 1)
+		go func() {
+			ran <- server.Run(ctx)
+		}()
+
+		Eventually(func() error {
+			_, err := http.Get("http://" + listener.Addr().String() + "/v2/catalog")
+			return err
+		}).Should(Succeed())
+
+		cancel()
+		Eventually(ran).Should(Receive(BeNil()))
+	})
+})